@@ -2,10 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Financial-Times/neo-utils-go/neoutils"
@@ -58,6 +61,118 @@ func main() {
 		log.Fatal("$API_KEY must be set")
 	}
 
+	contentAPIURL := os.Getenv("CONTENT_API_URL")
+
+	if contentAPIURL == "" {
+		contentAPIURL = "http://api.ft.com"
+	}
+
+	log.Printf("contentAPIURL=%s", contentAPIURL)
+
+	contentAPIConcurrency := envInt("CONTENT_API_CONCURRENCY", 16)
+
+	log.Printf("contentAPIConcurrency=%d", contentAPIConcurrency)
+
+	if contentAPIConcurrency < 1 {
+		log.Fatal("$CONTENT_API_CONCURRENCY must be at least 1")
+	}
+
+	contentAPITimeout := envDuration("CONTENT_API_TIMEOUT", 10*time.Second)
+
+	log.Printf("contentAPITimeout=%s", contentAPITimeout)
+
+	recReadsTimeout := envDuration("REC_READS_TIMEOUT", 5*time.Second)
+
+	log.Printf("recReadsTimeout=%s", recReadsTimeout)
+
+	recReadsRetries := envInt("REC_READS_RETRIES", 2)
+
+	log.Printf("recReadsRetries=%d", recReadsRetries)
+
+	recReadsBreakerThreshold := envInt("REC_READS_BREAKER_THRESHOLD", 5)
+
+	log.Printf("recReadsBreakerThreshold=%d", recReadsBreakerThreshold)
+
+	recReadsBreakerCooldown := envDuration("REC_READS_BREAKER_COOLDOWN", 30*time.Second)
+
+	log.Printf("recReadsBreakerCooldown=%s", recReadsBreakerCooldown)
+
+	cacheTTL := envDuration("CACHE_TTL", 1*time.Hour)
+
+	log.Printf("cacheTTL=%s", cacheTTL)
+
+	degradedCacheTTL := envDuration("DEGRADED_CACHE_TTL", 1*time.Minute)
+
+	log.Printf("degradedCacheTTL=%s", degradedCacheTTL)
+
+	cacheMaxEntries := envInt("CACHE_MAX_ENTRIES", 1000)
+
+	log.Printf("cacheMaxEntries=%d", cacheMaxEntries)
+
+	contentCacheTTL := envDuration("CONTENT_CACHE_TTL", 10*time.Minute)
+
+	log.Printf("contentCacheTTL=%s", contentCacheTTL)
+
+	contentCacheMaxEntries := envInt("CONTENT_CACHE_MAX_ENTRIES", 10000)
+
+	log.Printf("contentCacheMaxEntries=%d", contentCacheMaxEntries)
+
+	descriptionsFile := os.Getenv("DESCRIPTIONS_FILE")
+
+	if descriptionsFile == "" {
+		descriptionsFile = "descriptions.json"
+	}
+
+	log.Printf("descriptionsFile=%s", descriptionsFile)
+
+	descriptionsReloadInterval := envDuration("DESCRIPTIONS_RELOAD_INTERVAL", 1*time.Minute)
+
+	log.Printf("descriptionsReloadInterval=%s", descriptionsReloadInterval)
+
+	descriptionsStore := os.Getenv("DESCRIPTIONS_STORE")
+
+	if descriptionsStore == "" {
+		descriptionsStore = "descriptions-store.json"
+	}
+
+	log.Printf("descriptionsStore=%s", descriptionsStore)
+
+	overridesFile := os.Getenv("OVERRIDES_FILE")
+
+	if overridesFile == "" {
+		overridesFile = "overrides.json"
+	}
+
+	log.Printf("overridesFile=%s", overridesFile)
+
+	tagRulesFile := os.Getenv("TAG_RULES_FILE")
+
+	if tagRulesFile == "" {
+		tagRulesFile = "tag-rules.json"
+	}
+
+	log.Printf("tagRulesFile=%s", tagRulesFile)
+
+	sectionPriority := defaultSectionPriority
+
+	if v := os.Getenv("SECTION_PRIORITY"); v != "" {
+		priority, err := parseSectionPriority(v)
+		if err != nil {
+			log.Fatalf("Invalid $SECTION_PRIORITY %s", err)
+		}
+		sectionPriority = priority
+	}
+
+	log.Printf("sectionPriority=%v", sectionPriority)
+
+	batchConcurrency := envInt("BATCH_CONCURRENCY", 4)
+
+	log.Printf("batchConcurrency=%d", batchConcurrency)
+
+	if batchConcurrency < 1 {
+		log.Fatal("$BATCH_CONCURRENCY must be at least 1")
+	}
+
 	conf := neoutils.ConnectionConfig{
 		BatchSize:     1024,
 		Transactional: false,
@@ -75,16 +190,104 @@ func main() {
 		log.Fatalf("Error connecting to neo4j %s", err)
 	}
 
-	och := organisationContentHandler{newOrganisationContentService(db, recReadsURL, apiKey)}
+	fileDescriptions, err := newFileDescriptionProvider(descriptionsFile)
+
+	if err != nil {
+		log.Fatalf("Error loading descriptions %s", err)
+	}
+
+	storedDescriptions, err := newStoredDescriptionProvider(descriptionsStore)
+
+	if err != nil {
+		log.Fatalf("Error loading stored descriptions %s", err)
+	}
+
+	descriptions := descriptionProviders{storedDescriptions, fileDescriptions}
+
+	overrides, err := newOverridesStore(overridesFile)
+
+	if err != nil {
+		log.Fatalf("Error loading overrides %s", err)
+	}
+
+	tagRules, err := loadTagRules(tagRulesFile)
+
+	if err != nil {
+		log.Fatalf("Error loading tag rules %s", err)
+	}
+
+	log.Printf("Loaded %d tag rules", len(tagRules))
+
+	cache := newOrganisationCache(cacheTTL, degradedCacheTTL, cacheMaxEntries)
+
+	go fileDescriptions.watch(descriptionsReloadInterval, cache)
+
+	recReads := newRecommendedReadsClient(recReadsURL, recReadsTimeout, recReadsRetries, 200*time.Millisecond,
+		newCircuitBreaker(recReadsBreakerThreshold, recReadsBreakerCooldown))
+
+	contentCache := newContentCache(contentCacheTTL, contentCacheMaxEntries)
+	contentAPI := newEnrichedContentClient(contentAPIURL, apiKey, contentAPITimeout, contentCache)
+	enrichPool := newWorkerPool(contentAPIConcurrency)
+
+	och := organisationContentHandler{newOrganisationContentService(db, recReads, contentAPI, enrichPool, cache, descriptions, overrides, tagRules, sectionPriority, batchConcurrency)}
+	ch := cacheHandler{cache, contentCache}
+	dh := descriptionHandler{storedDescriptions, cache}
+	oh := overridesHandler{overrides, cache}
 
 	r := mux.NewRouter()
+	r.HandleFunc("/organisations", och.getContentForOrganisations).Methods("GET", "POST")
 	r.HandleFunc("/organisations/{uuid}", och.getContentRelatedToOrganisation).Methods("GET")
+	r.HandleFunc("/organisations/{uuid}/peers", och.getPeers).Methods("GET")
+	r.HandleFunc("/organisations/{uuid}/subsidiaries", och.getSubsidiaries).Methods("GET")
+	r.HandleFunc("/industries/{uuid}", och.getIndustry).Methods("GET")
+	r.HandleFunc("/industries/{uuid}/content", och.getIndustryContent).Methods("GET")
+	r.HandleFunc("/organisations/by-identifier/{authority}/{value}", och.getContentByIdentifier).Methods("GET")
+	r.HandleFunc("/__cache/stats", ch.getStats).Methods("GET")
+	r.HandleFunc("/__cache/content/stats", ch.getContentStats).Methods("GET")
+	r.HandleFunc("/__cache/organisations/{uuid}", ch.purgeOrganisation).Methods("DELETE")
+	r.HandleFunc("/__cache/organisations", ch.purgeAll).Methods("DELETE")
+	r.HandleFunc("/organisations/{uuid}/description", dh.putDescription).Methods("PUT")
+	r.HandleFunc("/__overrides", oh.getOverrides).Methods("GET")
+	r.HandleFunc("/__overrides/stories/{id}", oh.putStory).Methods("PUT")
+	r.HandleFunc("/__overrides/stories/{id}", oh.deleteStory).Methods("DELETE")
+	r.HandleFunc("/__overrides/organisations/{uuid}", oh.putOrganisation).Methods("PUT")
+	r.HandleFunc("/__overrides/organisations/{uuid}", oh.deleteOrganisation).Methods("DELETE")
 	r.HandleFunc("/__gtg", och.goodToGo).Methods("GET")
 	http.Handle("/", r)
 
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
 
+// envDuration reads a duration such as 30s from the environment variable
+// name, exiting if it is set but invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid $%s %s", name, err)
+	}
+	return d
+}
+
+// envInt reads an integer from the environment variable name, exiting if it
+// is set but invalid.
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("Invalid $%s %s", name, err)
+	}
+	return n
+}
+
 type organisationContentHandler struct {
 	ocs organisationContentService
 }
@@ -93,10 +296,18 @@ func (och *organisationContentHandler) getContentRelatedToOrganisation(writer ht
 	vars := mux.Vars(req)
 	uuid := vars["uuid"]
 
-	contentForRequestedOrganisation, found, err := och.ocs.getContentByOrganisationUUID(uuid)
+	opts, err := parseQueryOptions(req)
+
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentForRequestedOrganisation, found, err := och.ocs.getContentByOrganisationUUID(uuid, opts)
 
 	if err != nil {
 		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
 	}
 
 	if !found {
@@ -108,6 +319,369 @@ func (och *organisationContentHandler) getContentRelatedToOrganisation(writer ht
 	enc.Encode(contentForRequestedOrganisation)
 }
 
+func (och *organisationContentHandler) getContentByIdentifier(writer http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+
+	opts, err := parseQueryOptions(req)
+
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	uuid, found, err := och.ocs.resolveIdentifier(vars["authority"], vars["value"])
+
+	if _, unknown := err.(unknownAuthorityError); unknown {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	contentForRequestedOrganisation, found, err := och.ocs.getContentByOrganisationUUID(uuid, opts)
+
+	if err != nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	enc := json.NewEncoder(writer)
+	enc.Encode(contentForRequestedOrganisation)
+}
+
+// getPeers lists the organisations in the same industry, most mentioned
+// in the time window first. ?limit= caps how many are returned.
+func (och *organisationContentHandler) getPeers(writer http.ResponseWriter, req *http.Request) {
+	uuid := mux.Vars(req)["uuid"]
+
+	opts, err := parseQueryOptions(req)
+
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultPeersLimit
+
+	if v := req.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxLimit {
+			http.Error(writer, fmt.Sprintf("invalid limit: must be between 1 and %d", maxLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	peers, found, err := och.ocs.getPeers(uuid, opts, limit)
+
+	if err != nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(peers)
+}
+
+// getSubsidiaries returns the organisation's group structure as a tree.
+// ?depth= sets how many levels of subsidiaries are followed, and ?stories=
+// how many of each organisation's latest stories are included.
+func (och *organisationContentHandler) getSubsidiaries(writer http.ResponseWriter, req *http.Request) {
+	uuid := mux.Vars(req)["uuid"]
+
+	opts, err := parseQueryOptions(req)
+
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := req.URL.Query()
+	depth, stories := defaultSubsidiaryDepth, 0
+
+	if v := params.Get("depth"); v != "" {
+		if depth, err = strconv.Atoi(v); err != nil || depth < 1 || depth > maxSubsidiaryDepth {
+			http.Error(writer, fmt.Sprintf("invalid depth: must be between 1 and %d", maxSubsidiaryDepth), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if v := params.Get("stories"); v != "" {
+		if stories, err = strconv.Atoi(v); err != nil || stories < 0 || stories > maxLimit {
+			http.Error(writer, fmt.Sprintf("invalid stories: must be between 0 and %d", maxLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tree, found, err := och.ocs.getSubsidiaryTree(uuid, depth, stories, opts)
+
+	if err != nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(tree)
+}
+
+func (och *organisationContentHandler) getIndustry(writer http.ResponseWriter, req *http.Request) {
+	uuid := mux.Vars(req)["uuid"]
+
+	ind, found, err := och.ocs.getIndustry(uuid)
+
+	if err != nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(ind)
+}
+
+// getIndustryContent returns recent stories about any organisation in the
+// industry, accepting the same query parameters as an organisation's
+// stories section.
+func (och *organisationContentHandler) getIndustryContent(writer http.ResponseWriter, req *http.Request) {
+	uuid := mux.Vars(req)["uuid"]
+
+	opts, err := parseQueryOptions(req)
+
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ic, found, err := och.ocs.getIndustryContent(uuid, opts)
+
+	if err != nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(ic)
+}
+
+type batchBody struct {
+	UUIDs []string `json:"uuids"`
+}
+
+// getContentForOrganisations returns several organisations at once, keyed
+// by UUID. The UUIDs are given as a JSON body of {"uuids": [...]} when
+// posted, or as a comma-separated ?uuids= otherwise.
+func (och *organisationContentHandler) getContentForOrganisations(writer http.ResponseWriter, req *http.Request) {
+	opts, err := parseQueryOptions(req)
+
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body := batchBody{}
+
+	if req.Method == "POST" {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(writer, "invalid batch body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if v := req.URL.Query().Get("uuids"); v != "" {
+		for _, uuid := range strings.Split(v, ",") {
+			body.UUIDs = append(body.UUIDs, strings.TrimSpace(uuid))
+		}
+	}
+
+	if len(body.UUIDs) == 0 || len(body.UUIDs) > maxBatchSize {
+		http.Error(writer, fmt.Sprintf("between 1 and %d uuids must be given", maxBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	for _, uuid := range body.UUIDs {
+		if uuid == "" {
+			http.Error(writer, "uuids must not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := och.ocs.getContentByOrganisationUUIDs(body.UUIDs, opts)
+
+	if err != nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(entries)
+}
+
 func (och *organisationContentHandler) goodToGo(writer http.ResponseWriter, req *http.Request) {
 	writer.WriteHeader(http.StatusOK)
 }
+
+type cacheHandler struct {
+	cache        *organisationCache
+	contentCache *contentCache
+}
+
+func (ch *cacheHandler) getStats(writer http.ResponseWriter, req *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(ch.cache.stats())
+}
+
+func (ch *cacheHandler) getContentStats(writer http.ResponseWriter, req *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(ch.contentCache.stats())
+}
+
+func (ch *cacheHandler) purgeOrganisation(writer http.ResponseWriter, req *http.Request) {
+	uuid := mux.Vars(req)["uuid"]
+
+	n := ch.cache.purge(uuid)
+
+	if n == 0 {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Purged %d cached entries for org %s", n, uuid)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (ch *cacheHandler) purgeAll(writer http.ResponseWriter, req *http.Request) {
+	n := ch.cache.purgeAll()
+
+	log.Printf("Purged %d cached orgs", n)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+type descriptionHandler struct {
+	store *storedDescriptionProvider
+	cache *organisationCache
+}
+
+type descriptionBody struct {
+	Description string `json:"description"`
+}
+
+func (dh *descriptionHandler) putDescription(writer http.ResponseWriter, req *http.Request) {
+	uuid := mux.Vars(req)["uuid"]
+
+	body := descriptionBody{}
+
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(writer, "invalid description body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := dh.store.setDescription(uuid, body.Description); err != nil {
+		log.Printf("Could not store description for uuid=%s, err=%s", uuid, err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	dh.cache.purge(uuid)
+	log.Printf("Stored description for org %s", uuid)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+type overridesHandler struct {
+	store *overridesStore
+	cache *organisationCache
+}
+
+func (oh *overridesHandler) getOverrides(writer http.ResponseWriter, req *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(oh.store.all())
+}
+
+func (oh *overridesHandler) putStory(writer http.ResponseWriter, req *http.Request) {
+	o := storyOverride{}
+
+	if err := json.NewDecoder(req.Body).Decode(&o); err != nil {
+		http.Error(writer, "invalid story override: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	oh.setStory(writer, mux.Vars(req)["id"], &o)
+}
+
+func (oh *overridesHandler) deleteStory(writer http.ResponseWriter, req *http.Request) {
+	oh.setStory(writer, mux.Vars(req)["id"], nil)
+}
+
+// setStory stores or, if o is nil, removes a story override. A story can
+// appear for any organisation, so the whole cache is purged.
+func (oh *overridesHandler) setStory(writer http.ResponseWriter, id string, o *storyOverride) {
+	if err := oh.store.setStory(id, o); err != nil {
+		log.Printf("Could not store override for story=%s, err=%s", id, err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	oh.cache.purgeAll()
+	log.Printf("Updated override for story %s", id)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (oh *overridesHandler) putOrganisation(writer http.ResponseWriter, req *http.Request) {
+	o := organisationOverride{}
+
+	if err := json.NewDecoder(req.Body).Decode(&o); err != nil {
+		http.Error(writer, "invalid organisation override: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for section := range o.Pinned {
+		if !isSection(section) {
+			http.Error(writer, "invalid organisation override: unknown section "+section, http.StatusBadRequest)
+			return
+		}
+	}
+
+	oh.setOrganisation(writer, mux.Vars(req)["uuid"], &o)
+}
+
+func (oh *overridesHandler) deleteOrganisation(writer http.ResponseWriter, req *http.Request) {
+	oh.setOrganisation(writer, mux.Vars(req)["uuid"], nil)
+}
+
+func (oh *overridesHandler) setOrganisation(writer http.ResponseWriter, uuid string, o *organisationOverride) {
+	if err := oh.store.setOrganisation(uuid, o); err != nil {
+		log.Printf("Could not store override for org=%s, err=%s", uuid, err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	oh.cache.purge(uuid)
+	log.Printf("Updated override for org %s", uuid)
+	writer.WriteHeader(http.StatusNoContent)
+}
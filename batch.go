@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jmcvetta/neoism"
+)
+
+// maxBatchSize is the most organisations that can be requested at once.
+const maxBatchSize = 50
+
+// batchEntry is the outcome of one organisation in a batch request.
+type batchEntry struct {
+	Status       int           `json:"status"`
+	Organisation *organisation `json:"organisation,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// getContentByOrganisationUUIDs loads several organisations at once. The
+// queries for every uncached organisation go to Neo4j in a single batch, and
+// the organisations found are then assembled at most ocs.batchConcurrency at
+// a time. Each organisation's outcome is reported in its own entry; an error
+// is only returned if the shared batch fails.
+func (ocs simpleOrganisationContentService) getContentByOrganisationUUIDs(uuids []string, opts queryOptions) (map[string]batchEntry, error) {
+	entries := map[string]batchEntry{}
+	loads := []*organisationLoad{}
+	queries := []*neoism.CypherQuery{}
+	now := time.Now()
+
+	for _, uuid := range uuids {
+		if _, seen := entries[uuid]; seen {
+			continue
+		}
+
+		if org, found := ocs.cache.get(opts.cacheKey(uuid)); found {
+			entries[uuid] = batchEntry{Status: http.StatusOK, Organisation: &org}
+			continue
+		}
+
+		// Placeholder until the organisation is loaded, marking it as seen.
+		entries[uuid] = batchEntry{}
+
+		load := newOrganisationLoad(uuid, opts, now, ocs.cache.generation(uuid))
+		loads = append(loads, load)
+		queries = append(queries, load.queries...)
+	}
+
+	if len(queries) > 0 {
+		if err := ocs.conn.CypherBatch(queries); err != nil {
+			return nil, err
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, ocs.batchConcurrency)
+
+	for _, load := range loads {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(load *organisationLoad) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			org, found, err := ocs.inflight.do(opts.cacheKey(load.uuid), func() (organisation, bool, error) {
+				return ocs.assembleOrganisation(load)
+			})
+
+			entry := batchEntry{Status: http.StatusOK, Organisation: &org}
+
+			if err != nil {
+				log.Printf("Could not load org %s in batch, err=%s", load.uuid, err)
+				entry = batchEntry{Status: http.StatusServiceUnavailable, Error: "organisation unavailable"}
+			} else if !found {
+				entry = batchEntry{Status: http.StatusNotFound, Error: "organisation not found"}
+			}
+
+			mu.Lock()
+			entries[load.uuid] = entry
+			mu.Unlock()
+		}(load)
+	}
+
+	wg.Wait()
+
+	return entries, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmcvetta/neoism"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchRunsEverySectionInOneCypherBatch(t *testing.T) {
+	opts := defaultQueryOptions()
+	opts.include = map[string]bool{}
+	for _, section := range sections {
+		if section != recommendedSection {
+			opts.include[section] = true
+		}
+	}
+
+	conn := &fakeConn{}
+	ocs := newTestService(t, conn)
+
+	var queries int
+	conn.fn = func(batch []*neoism.CypherQuery) error {
+		queries = len(batch)
+
+		for _, q := range batch {
+			if result, ok := q.Result.(*[]organisation); ok && q.Parameters["uuid"] == "acme" {
+				*result = []organisation{{ID: "acme", Title: "Acme", Description: "From the graph"}}
+			}
+		}
+		return nil
+	}
+
+	entries, err := ocs.getContentByOrganisationUUIDs([]string{"acme", "missing"}, opts)
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&conn.calls))
+	// The organisation, its stories, profile and five related sections, for
+	// each of the two organisations.
+	assert.Equal(t, 16, queries)
+
+	assert.Equal(t, http.StatusOK, entries["acme"].Status)
+	assert.Equal(t, "From the graph", entries["acme"].Organisation.Description)
+	assert.Equal(t, http.StatusNotFound, entries["missing"].Status)
+}
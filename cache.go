@@ -0,0 +1,186 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// organisationCache is a size-bounded cache of organisation payloads. Entries
+// expire after ttl, or degradedTTL for payloads with warnings, and the least
+// recently used entry is evicted once the cache holds maxEntries.
+//
+// Every purge starts a new generation of the organisations it removes, so
+// that a payload loaded from before the purge isn't cached after it.
+type organisationCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	degradedTTL time.Duration
+	maxEntries  int
+	ll          *list.List
+	entries     map[string]*list.Element
+	lastPurge   uint64
+	purgedAll   uint64
+	purged      map[string]uint64
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+	now         func() time.Time
+}
+
+type cacheEntry struct {
+	key     string
+	uuid    string
+	org     organisation
+	expires time.Time
+}
+
+type cacheStats struct {
+	Entries     int    `json:"entries"`
+	MaxEntries  int    `json:"maxEntries"`
+	TTL         string `json:"ttl"`
+	DegradedTTL string `json:"degradedTTL"`
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	Evictions   uint64 `json:"evictions"`
+	Expirations uint64 `json:"expirations"`
+}
+
+func newOrganisationCache(ttl time.Duration, degradedTTL time.Duration, maxEntries int) *organisationCache {
+	return &organisationCache{
+		ttl:         ttl,
+		degradedTTL: degradedTTL,
+		maxEntries:  maxEntries,
+		ll:          list.New(),
+		entries:     map[string]*list.Element{},
+		purged:      map[string]uint64{},
+		now:         time.Now,
+	}
+}
+
+func (c *organisationCache) get(key string) (organisation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.entries[key]
+	if !found {
+		c.misses++
+		return organisation{}, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if c.now().After(entry.expires) {
+		c.removeElement(el)
+		c.expirations++
+		c.misses++
+		return organisation{}, false
+	}
+
+	c.ll.MoveToFront(el)
+	c.hits++
+	return entry.org, true
+}
+
+// generation identifies the last purge of the organisation uuid. It is
+// taken before loading the organisation and passed back to set.
+func (c *organisationCache) generation(uuid string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generationLocked(uuid)
+}
+
+func (c *organisationCache) generationLocked(uuid string) uint64 {
+	if gen := c.purged[uuid]; gen > c.purgedAll {
+		return gen
+	}
+	return c.purgedAll
+}
+
+// set caches org under key for ttl, unless the organisation uuid has been
+// purged since generation was taken and org may be out of date. It reports
+// whether org was cached.
+func (c *organisationCache) set(key string, uuid string, org organisation, ttl time.Duration, generation uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generationLocked(uuid) != generation {
+		return false
+	}
+
+	expires := c.now().Add(ttl)
+
+	if el, found := c.entries[key]; found {
+		entry := el.Value.(*cacheEntry)
+		entry.org = org
+		entry.expires = expires
+		c.ll.MoveToFront(el)
+		return true
+	}
+
+	c.entries[key] = c.ll.PushFront(&cacheEntry{key: key, uuid: uuid, org: org, expires: expires})
+
+	for c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+		c.evictions++
+	}
+	return true
+}
+
+// purge removes every entry cached for the organisation uuid, whatever the
+// options it was requested with, and returns the number removed.
+func (c *organisationCache) purge(uuid string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastPurge++
+	c.purged[uuid] = c.lastPurge
+
+	n := 0
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*cacheEntry).uuid == uuid {
+			c.removeElement(el)
+			n++
+		}
+		el = next
+	}
+	return n
+}
+
+// purgeAll empties the cache and returns the number of entries removed.
+func (c *organisationCache) purgeAll() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastPurge++
+	c.purgedAll = c.lastPurge
+	c.purged = map[string]uint64{}
+
+	n := c.ll.Len()
+	c.ll.Init()
+	c.entries = map[string]*list.Element{}
+	return n
+}
+
+func (c *organisationCache) stats() cacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return cacheStats{
+		Entries:     c.ll.Len(),
+		MaxEntries:  c.maxEntries,
+		TTL:         c.ttl.String(),
+		DegradedTTL: c.degradedTTL.String(),
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+	}
+}
+
+func (c *organisationCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
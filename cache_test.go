@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a settable time for the caches' now hooks.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestCache(ttl time.Duration, maxEntries int) (*organisationCache, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := newOrganisationCache(ttl, ttl/10, maxEntries)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestCacheExpiresEntriesAfterTTL(t *testing.T) {
+	c, clock := newTestCache(time.Minute, 10)
+
+	c.set("a?", "a", organisation{ID: "a"}, c.ttl, 0)
+
+	clock.t = clock.t.Add(time.Minute)
+	org, found := c.get("a?")
+	assert.True(t, found)
+	assert.Equal(t, "a", org.ID)
+
+	clock.t = clock.t.Add(time.Second)
+	_, found = c.get("a?")
+	assert.False(t, found)
+
+	stats := c.stats()
+	assert.Equal(t, 0, stats.Entries)
+	assert.EqualValues(t, 1, stats.Hits)
+	assert.EqualValues(t, 1, stats.Misses)
+	assert.EqualValues(t, 1, stats.Expirations)
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c, _ := newTestCache(time.Hour, 2)
+
+	c.set("a?", "a", organisation{ID: "a"}, c.ttl, 0)
+	c.set("b?", "b", organisation{ID: "b"}, c.ttl, 0)
+
+	// Using a makes b the least recently used.
+	_, found := c.get("a?")
+	assert.True(t, found)
+
+	c.set("c?", "c", organisation{ID: "c"}, c.ttl, 0)
+
+	_, found = c.get("b?")
+	assert.False(t, found)
+	_, found = c.get("a?")
+	assert.True(t, found)
+	_, found = c.get("c?")
+	assert.True(t, found)
+
+	assert.EqualValues(t, 1, c.stats().Evictions)
+}
+
+func TestCacheSetRefreshesExistingEntry(t *testing.T) {
+	c, clock := newTestCache(time.Minute, 10)
+
+	c.set("a?", "a", organisation{Title: "old"}, c.ttl, 0)
+	clock.t = clock.t.Add(50 * time.Second)
+	c.set("a?", "a", organisation{Title: "new"}, c.ttl, 0)
+	clock.t = clock.t.Add(50 * time.Second)
+
+	org, found := c.get("a?")
+	assert.True(t, found)
+	assert.Equal(t, "new", org.Title)
+	assert.Equal(t, 1, c.stats().Entries)
+}
+
+func TestCachePurgesEveryKeyForAnOrganisation(t *testing.T) {
+	c, _ := newTestCache(time.Hour, 10)
+
+	c.set("a?", "a", organisation{}, c.ttl, 0)
+	c.set("a?window=30d", "a", organisation{}, c.ttl, 0)
+	c.set("b?", "b", organisation{}, c.ttl, 0)
+
+	assert.Equal(t, 2, c.purge("a"))
+	assert.Equal(t, 0, c.purge("a"))
+
+	_, found := c.get("b?")
+	assert.True(t, found)
+
+	assert.Equal(t, 1, c.purgeAll())
+	assert.Equal(t, 0, c.stats().Entries)
+}
+
+func TestCacheSetHonoursTTL(t *testing.T) {
+	c, clock := newTestCache(time.Hour, 10)
+
+	c.set("a?", "a", organisation{}, c.ttl, 0)
+	c.set("b?", "b", organisation{Warnings: []warning{{Message: "gone"}}}, c.degradedTTL, 0)
+
+	clock.t = clock.t.Add(7 * time.Minute)
+
+	_, found := c.get("a?")
+	assert.True(t, found)
+	_, found = c.get("b?")
+	assert.False(t, found, "degraded entry expired")
+}
+
+func TestCacheSkipsPayloadsLoadedBeforeAPurge(t *testing.T) {
+	c, _ := newTestCache(time.Hour, 10)
+
+	a, b := c.generation("a"), c.generation("b")
+	c.purge("a")
+
+	assert.False(t, c.set("a?", "a", organisation{}, c.ttl, a), "a was purged while loading")
+	assert.True(t, c.set("b?", "b", organisation{}, c.ttl, b), "b wasn't")
+	assert.True(t, c.set("a?", "a", organisation{}, c.ttl, c.generation("a")), "a loaded after the purge")
+
+	a, b = c.generation("a"), c.generation("b")
+	c.purgeAll()
+
+	assert.False(t, c.set("a?", "a", organisation{}, c.ttl, a))
+	assert.False(t, c.set("b?", "b", organisation{}, c.ttl, b))
+	assert.Equal(t, 0, c.stats().Entries)
+}
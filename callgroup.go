@@ -0,0 +1,59 @@
+package main
+
+import "sync"
+
+// callGroup coalesces concurrent loads of the same key so that only one
+// caller does the work and the others wait for and share its result.
+type callGroup struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	wg    sync.WaitGroup
+	org   organisation
+	found bool
+	err   error
+	dups  int
+}
+
+func newCallGroup() *callGroup {
+	return &callGroup{calls: map[string]*call{}}
+}
+
+// waiting is the number of callers sharing the load of key in flight.
+func (g *callGroup) waiting(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c, found := g.calls[key]; found {
+		return c.dups
+	}
+	return 0
+}
+
+func (g *callGroup) do(key string, fn func() (organisation, bool, error)) (organisation, bool, error) {
+	g.mu.Lock()
+	if c, found := g.calls[key]; found {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.org, c.found, c.err
+	}
+
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.org, c.found, c.err = fn()
+
+	return c.org, c.found, c.err
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmcvetta/neoism"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn is a neoutils.NeoConnection that counts batches and answers them
+// with fn.
+type fakeConn struct {
+	calls int32
+	fn    func(queries []*neoism.CypherQuery) error
+}
+
+func (c *fakeConn) CypherBatch(queries []*neoism.CypherQuery) error {
+	atomic.AddInt32(&c.calls, 1)
+	if c.fn == nil {
+		return nil
+	}
+	return c.fn(queries)
+}
+
+func (c *fakeConn) EnsureConstraints(indexes map[string]string) error { return nil }
+
+func (c *fakeConn) EnsureIndexes(indexes map[string]string) error { return nil }
+
+func newTestService(t *testing.T, conn *fakeConn) simpleOrganisationContentService {
+	overrides, err := newOverridesStore(t.TempDir() + "/overrides.json")
+	assert.NoError(t, err)
+
+	return newOrganisationContentService(conn, nil, nil, newWorkerPool(1), newOrganisationCache(time.Hour, time.Minute, 10), descriptionProviders{}, overrides, nil, defaultSectionPriority, 1)
+}
+
+// waitFor polls cond until it holds, reporting an error and returning false
+// if it doesn't within a few seconds. It doesn't stop the test, so it can be
+// called from goroutines other than the test's own.
+func waitFor(t *testing.T, cond func() bool) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Error("timed out waiting")
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+func TestCallGroupCoalescesConcurrentCalls(t *testing.T) {
+	g := newCallGroup()
+
+	const callers = 10
+	var loads int32
+	var wg sync.WaitGroup
+	results := make([]organisation, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, _ = g.do("key", func() (organisation, bool, error) {
+				atomic.AddInt32(&loads, 1)
+				waitFor(t, func() bool { return g.waiting("key") == callers-1 })
+				return organisation{ID: "org"}, true, nil
+			})
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.EqualValues(t, 1, loads)
+	for _, org := range results {
+		assert.Equal(t, "org", org.ID)
+	}
+	assert.Equal(t, 0, g.waiting("key"))
+}
+
+func TestConcurrentColdMissesRunOneBatch(t *testing.T) {
+	const callers = 10
+	const uuid = "org-uuid"
+
+	opts := defaultQueryOptions()
+	opts.include = map[string]bool{storiesSection: true}
+
+	conn := &fakeConn{}
+	ocs := newTestService(t, conn)
+
+	conn.fn = func(queries []*neoism.CypherQuery) error {
+		waitFor(t, func() bool { return ocs.inflight.waiting(opts.cacheKey(uuid)) == callers-1 })
+
+		for _, q := range queries {
+			if result, ok := q.Result.(*[]organisation); ok {
+				*result = []organisation{{ID: uuid, Title: "Acme"}}
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			org, found, err := ocs.getContentByOrganisationUUID(uuid, opts)
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, "Acme", org.Title)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&conn.calls))
+
+	// The result is now cached.
+	_, found, err := ocs.getContentByOrganisationUUID(uuid, opts)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&conn.calls))
+}
+
+func TestPurgeDuringLoadIsNotCached(t *testing.T) {
+	const uuid = "org-uuid"
+
+	opts := defaultQueryOptions()
+	opts.include = map[string]bool{storiesSection: true}
+
+	conn := &fakeConn{}
+	ocs := newTestService(t, conn)
+
+	purge := true
+	conn.fn = func(queries []*neoism.CypherQuery) error {
+		if purge {
+			// An editor's change arrives while the organisation is loading.
+			ocs.cache.purge(uuid)
+			purge = false
+		}
+
+		for _, q := range queries {
+			if result, ok := q.Result.(*[]organisation); ok {
+				*result = []organisation{{ID: uuid, Title: "Acme"}}
+			}
+		}
+		return nil
+	}
+
+	for i := 1; i <= 3; i++ {
+		_, found, err := ocs.getContentByOrganisationUUID(uuid, opts)
+		assert.NoError(t, err)
+		assert.True(t, found)
+	}
+
+	// The first load was purged, so the second loads again and is cached.
+	assert.EqualValues(t, 2, atomic.LoadInt32(&conn.calls))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// contentCache is a size-bounded cache of Content API responses keyed by
+// request URL and shared by every organisation. Entries are served as they
+// are until ttl has passed, and are then revalidated with their ETag rather
+// than fetched again. Concurrent fetches of the same URL are collapsed into
+// one.
+type contentCache struct {
+	mu            sync.Mutex
+	ttl           time.Duration
+	maxEntries    int
+	ll            *list.List
+	entries       map[string]*list.Element
+	inflight      map[string]*contentCall
+	hits          uint64
+	misses        uint64
+	revalidations uint64
+	evictions     uint64
+	now           func() time.Time
+}
+
+type contentCacheEntry struct {
+	url     string
+	etag    string
+	content enrichedContent
+	expires time.Time
+}
+
+type contentCall struct {
+	wg      sync.WaitGroup
+	content enrichedContent
+	err     error
+	dups    int
+}
+
+type contentCacheStats struct {
+	Entries       int    `json:"entries"`
+	MaxEntries    int    `json:"maxEntries"`
+	TTL           string `json:"ttl"`
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Revalidations uint64 `json:"revalidations"`
+	Evictions     uint64 `json:"evictions"`
+}
+
+// contentFetcher requests url, conditionally on etag if it is set. It
+// reports notModified if the Content API answered 304 Not Modified.
+type contentFetcher func(url string, etag string) (content enrichedContent, newETag string, notModified bool, err error)
+
+func newContentCache(ttl time.Duration, maxEntries int) *contentCache {
+	return &contentCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		entries:    map[string]*list.Element{},
+		inflight:   map[string]*contentCall{},
+		now:        time.Now,
+	}
+}
+
+// get returns the content at url, from the cache if it is fresh and
+// otherwise by calling fetch. Content the Content API confirms is unchanged
+// is kept for another ttl.
+func (c *contentCache) get(url string, fetch contentFetcher) (enrichedContent, error) {
+	c.mu.Lock()
+
+	var stale *contentCacheEntry
+
+	if el, found := c.entries[url]; found {
+		entry := el.Value.(*contentCacheEntry)
+		if !c.now().After(entry.expires) {
+			c.ll.MoveToFront(el)
+			c.hits++
+			c.mu.Unlock()
+			return entry.content, nil
+		}
+		stale = entry
+	}
+
+	if call, found := c.inflight[url]; found {
+		call.dups++
+		c.mu.Unlock()
+		call.wg.Wait()
+		return call.content, call.err
+	}
+
+	call := &contentCall{}
+	call.wg.Add(1)
+	c.inflight[url] = call
+	c.misses++
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, url)
+		c.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	etag := ""
+	if stale != nil {
+		etag = stale.etag
+	}
+
+	content, newETag, notModified, err := fetch(url, etag)
+	if err != nil {
+		call.err = err
+		return enrichedContent{}, err
+	}
+
+	if notModified && stale != nil {
+		content, newETag = stale.content, stale.etag
+	}
+
+	c.set(url, newETag, content, notModified)
+
+	call.content = content
+	return content, nil
+}
+
+// waiting is the number of callers sharing the fetch of url in flight.
+func (c *contentCache) waiting(url string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if call, found := c.inflight[url]; found {
+		return call.dups
+	}
+	return 0
+}
+
+func (c *contentCache) set(url string, etag string, content enrichedContent, revalidated bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if revalidated {
+		c.revalidations++
+	}
+
+	expires := c.now().Add(c.ttl)
+
+	if el, found := c.entries[url]; found {
+		entry := el.Value.(*contentCacheEntry)
+		entry.etag = etag
+		entry.content = content
+		entry.expires = expires
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.entries[url] = c.ll.PushFront(&contentCacheEntry{url: url, etag: etag, content: content, expires: expires})
+
+	for c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.entries, el.Value.(*contentCacheEntry).url)
+		c.evictions++
+	}
+}
+
+func (c *contentCache) stats() contentCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return contentCacheStats{
+		Entries:       c.ll.Len(),
+		MaxEntries:    c.maxEntries,
+		TTL:           c.ttl.String(),
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Revalidations: c.revalidations,
+		Evictions:     c.evictions,
+	}
+}
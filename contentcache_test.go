@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingFetcher is a contentFetcher that counts its calls and records the
+// ETags it was sent, answering with fn.
+type countingFetcher struct {
+	calls int32
+	mu    sync.Mutex
+	etags []string
+	fn    func(url string, etag string) (enrichedContent, string, bool, error)
+}
+
+func (f *countingFetcher) fetch(url string, etag string) (enrichedContent, string, bool, error) {
+	atomic.AddInt32(&f.calls, 1)
+
+	f.mu.Lock()
+	f.etags = append(f.etags, etag)
+	f.mu.Unlock()
+
+	return f.fn(url, etag)
+}
+
+// serving answers every fetch with a fresh copy of content under etag, or
+// 304 Not Modified if it was asked for etag.
+func serving(content enrichedContent, etag string) func(string, string) (enrichedContent, string, bool, error) {
+	return func(url string, sent string) (enrichedContent, string, bool, error) {
+		if sent == etag {
+			return enrichedContent{}, "", true, nil
+		}
+		return content, etag, false, nil
+	}
+}
+
+func newTestContentCache(ttl time.Duration, maxEntries int) (*contentCache, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := newContentCache(ttl, maxEntries)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestContentCacheCollapsesConcurrentFetches(t *testing.T) {
+	const callers = 10
+
+	c, _ := newTestContentCache(time.Minute, 10)
+	f := &countingFetcher{}
+	f.fn = func(url string, etag string) (enrichedContent, string, bool, error) {
+		waitFor(t, func() bool { return c.waiting(url) == callers-1 })
+		return enrichedContent{Title: "Acme"}, `"v1"`, false, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := c.get("/content/a", f.fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, "Acme", got.Title)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&f.calls))
+	assert.Equal(t, 0, c.waiting("/content/a"))
+
+	_, err := c.get("/content/a", f.fetch)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&f.calls), "served from the cache")
+	assert.EqualValues(t, 1, c.stats().Hits)
+}
+
+func TestContentCacheSharesErrorsWithWaiters(t *testing.T) {
+	const callers = 5
+
+	c, _ := newTestContentCache(time.Minute, 10)
+	f := &countingFetcher{}
+	f.fn = func(url string, etag string) (enrichedContent, string, bool, error) {
+		waitFor(t, func() bool { return c.waiting(url) == callers-1 })
+		return enrichedContent{}, "", false, errors.New("unavailable")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.get("/content/a", f.fetch)
+			assert.EqualError(t, err, "unavailable")
+		}()
+	}
+
+	wg.Wait()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&f.calls))
+	assert.Equal(t, 0, c.stats().Entries, "errors aren't cached")
+}
+
+func TestContentCacheRevalidatesExpiredEntries(t *testing.T) {
+	c, clock := newTestContentCache(time.Minute, 10)
+	f := &countingFetcher{fn: serving(enrichedContent{Title: "Acme"}, `"v1"`)}
+
+	_, err := c.get("/content/a", f.fetch)
+	assert.NoError(t, err)
+
+	clock.t = clock.t.Add(time.Minute)
+	got, err := c.get("/content/a", f.fetch)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&f.calls), "still fresh at ttl")
+
+	clock.t = clock.t.Add(time.Second)
+	got, err = c.get("/content/a", f.fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "Acme", got.Title, "kept the cached content on 304")
+	assert.Equal(t, []string{"", `"v1"`}, f.etags, "revalidated with its ETag")
+	assert.EqualValues(t, 1, c.stats().Revalidations)
+
+	// The revalidated entry is fresh for another ttl.
+	clock.t = clock.t.Add(time.Minute)
+	_, err = c.get("/content/a", f.fetch)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&f.calls))
+}
+
+func TestContentCacheReplacesChangedContent(t *testing.T) {
+	c, clock := newTestContentCache(time.Minute, 10)
+	f := &countingFetcher{fn: serving(enrichedContent{Title: "Old"}, `"v1"`)}
+
+	_, err := c.get("/content/a", f.fetch)
+	assert.NoError(t, err)
+
+	f.fn = serving(enrichedContent{Title: "New"}, `"v2"`)
+	clock.t = clock.t.Add(2 * time.Minute)
+
+	got, err := c.get("/content/a", f.fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "New", got.Title)
+
+	clock.t = clock.t.Add(2 * time.Minute)
+	_, err = c.get("/content/a", f.fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", `"v1"`, `"v2"`}, f.etags, "revalidated with the new ETag")
+	assert.EqualValues(t, 1, c.stats().Revalidations)
+}
+
+func TestContentCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c, _ := newTestContentCache(time.Hour, 2)
+	f := &countingFetcher{fn: func(url string, etag string) (enrichedContent, string, bool, error) {
+		return enrichedContent{Title: url}, "", false, nil
+	}}
+
+	c.get("/content/a", f.fetch)
+	c.get("/content/b", f.fetch)
+	c.get("/content/a", f.fetch)
+	c.get("/content/c", f.fetch)
+
+	assert.EqualValues(t, 3, atomic.LoadInt32(&f.calls))
+	assert.EqualValues(t, 1, c.stats().Evictions)
+
+	c.get("/content/a", f.fetch)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&f.calls), "a was used recently and kept")
+
+	c.get("/content/b", f.fetch)
+	assert.EqualValues(t, 4, atomic.LoadInt32(&f.calls), "b was evicted")
+	assert.Equal(t, 2, c.stats().Entries)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultSectionPriority orders sections from most to least specific to the
+// organisation.
+var defaultSectionPriority = []string{
+	storiesSection,
+	peopleSection,
+	subsidiariesSection,
+	parentsSection,
+	siblingsSection,
+	industrySection,
+	recommendedSection,
+}
+
+// parseSectionPriority reads a comma-separated list of sections. Sections
+// left out rank below those given, in their default order.
+func parseSectionPriority(v string) ([]string, error) {
+	priority := []string{}
+	listed := map[string]bool{}
+
+	for _, section := range strings.Split(v, ",") {
+		section = strings.TrimSpace(section)
+		if !isSection(section) {
+			return nil, fmt.Errorf("unknown section %q", section)
+		}
+		if !listed[section] {
+			listed[section] = true
+			priority = append(priority, section)
+		}
+	}
+
+	for _, section := range defaultSectionPriority {
+		if !listed[section] {
+			priority = append(priority, section)
+		}
+	}
+
+	return priority, nil
+}
+
+// dedupeSections leaves each story only in the highest priority section it
+// appears in, or the section it is pinned to, carrying the tags it had in
+// every section.
+func dedupeSections(org *organisation, priority []string, pinnedTo map[string]string) {
+	owner := map[string]string{}
+	tags := map[string][]tag{}
+
+	for id, section := range pinnedTo {
+		owner[id] = section
+	}
+
+	for _, section := range priority {
+		for _, story := range *org.section(section) {
+			if _, found := owner[story.ID]; !found {
+				owner[story.ID] = section
+			}
+			tags[story.ID] = mergeTags(tags[story.ID], story.Tags)
+		}
+	}
+
+	placed := map[string]bool{}
+
+	for _, section := range priority {
+		stories := org.section(section)
+		if *stories == nil {
+			continue
+		}
+
+		kept := (*stories)[:0]
+		for _, story := range *stories {
+			if owner[story.ID] != section || placed[story.ID] {
+				continue
+			}
+			placed[story.ID] = true
+			story.Tags = tags[story.ID]
+			kept = append(kept, story)
+		}
+		*stories = kept
+	}
+}
+
+func mergeTags(tags []tag, more []tag) []tag {
+	for _, t := range more {
+		found := false
+		for _, existing := range tags {
+			if existing == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
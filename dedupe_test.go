@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSectionPriority(t *testing.T) {
+	got, err := parseSectionPriority("industry, people,industry")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{industrySection, peopleSection, storiesSection, subsidiariesSection, parentsSection, siblingsSection, recommendedSection}, got)
+
+	_, err = parseSectionPriority("stories,board")
+	assert.Error(t, err)
+}
+
+func storyIDs(stories []content) []string {
+	ids := []string{}
+	for _, story := range stories {
+		ids = append(ids, story.ID)
+	}
+	return ids
+}
+
+func TestDedupeSections(t *testing.T) {
+	acme := tag{ID: "acme", Label: "Acme"}
+	jane := tag{ID: "jane", Label: "Jane Doe", Role: "CEO"}
+	peer := tag{ID: "peer", Label: "Peer"}
+
+	org := organisation{
+		Stories:         []content{{ID: "a"}, {ID: "b"}},
+		PeopleStories:   []content{{ID: "c", Tags: []tag{jane}}, {ID: "a", Tags: []tag{jane}}},
+		IndClassStories: []content{{ID: "c", Tags: []tag{peer}}, {ID: "d", Tags: []tag{peer}}, {ID: "a", Tags: []tag{acme, peer}}},
+		SubsidStories:   []content{{ID: "e"}, {ID: "e"}},
+	}
+
+	dedupeSections(&org, defaultSectionPriority, nil)
+
+	assert.Equal(t, []string{"a", "b"}, storyIDs(org.Stories))
+	assert.Equal(t, []string{"c"}, storyIDs(org.PeopleStories))
+	assert.Equal(t, []string{"d"}, storyIDs(org.IndClassStories))
+	assert.Equal(t, []string{"e"}, storyIDs(org.SubsidStories), "duplicates within a section")
+	assert.Nil(t, org.ParentStories)
+
+	assert.Equal(t, []tag{jane, acme, peer}, org.Stories[0].Tags, "tags from every section")
+	assert.Equal(t, []tag{jane, peer}, org.PeopleStories[0].Tags)
+}
+
+func TestDedupeSectionsByPriority(t *testing.T) {
+	org := organisation{
+		Stories:         []content{{ID: "a"}, {ID: "b"}},
+		IndClassStories: []content{{ID: "b"}},
+	}
+
+	dedupeSections(&org, []string{industrySection, storiesSection}, nil)
+
+	assert.Equal(t, []string{"a"}, storyIDs(org.Stories))
+	assert.Equal(t, []string{"b"}, storyIDs(org.IndClassStories))
+}
+
+func TestDedupedSectionsPageWithoutGapsOrRepeats(t *testing.T) {
+	org := organisation{ID: "org"}
+
+	for i := 0; i < 12; i++ {
+		story := content{ID: string(rune('a' + i))}
+		org.IndClassStories = append(org.IndClassStories, story)
+		if i%3 == 0 {
+			org.Stories = append(org.Stories, story)
+		}
+	}
+
+	dedupeSections(&org, defaultSectionPriority, nil)
+
+	seen := []string{}
+	for offset := 0; ; offset += 3 {
+		opts := defaultQueryOptions()
+		opts.pages[industrySection] = page{limit: 3, offset: offset}
+
+		paged := organisation{ID: "org"}
+		stories := opts.paginate(&paged, industrySection, org.IndClassStories)
+		seen = append(seen, storyIDs(stories)...)
+
+		if paged.Next[industrySection] == "" {
+			assert.True(t, len(stories) <= 3)
+			break
+		}
+		assert.Len(t, stories, 3, "full pages until the last")
+	}
+
+	assert.Equal(t, []string{"b", "c", "e", "f", "h", "i", "k", "l"}, seen)
+}
+
+func TestDedupeSectionsKeepsPinnedStoriesInPlace(t *testing.T) {
+	org := organisation{
+		Stories:         []content{{ID: "a"}, {ID: "b"}},
+		IndClassStories: []content{{ID: "b"}, {ID: "c"}},
+	}
+
+	dedupeSections(&org, defaultSectionPriority, map[string]string{"b": industrySection})
+
+	assert.Equal(t, []string{"a"}, storyIDs(org.Stories))
+	assert.Equal(t, []string{"b", "c"}, storyIDs(org.IndClassStories))
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// descriptionProvider supplies the editorial description of an organisation.
+type descriptionProvider interface {
+	getDescription(uuid string) (string, bool, error)
+}
+
+// descriptionProviders asks each provider in turn, returning the first
+// description found.
+type descriptionProviders []descriptionProvider
+
+func (dp descriptionProviders) getDescription(uuid string) (string, bool, error) {
+	for _, p := range dp {
+		desc, found, err := p.getDescription(uuid)
+		if err != nil {
+			return "", false, err
+		}
+		if found {
+			return desc, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// fileDescriptionProvider serves descriptions from a JSON object of UUID to
+// description, reloading it when the file changes.
+type fileDescriptionProvider struct {
+	path         string
+	mu           sync.RWMutex
+	descriptions map[string]string
+	modTime      time.Time
+}
+
+func newFileDescriptionProvider(path string) (*fileDescriptionProvider, error) {
+	p := &fileDescriptionProvider{path: path, descriptions: map[string]string{}}
+	_, err := p.reload()
+	return p, err
+}
+
+func (p *fileDescriptionProvider) getDescription(uuid string) (string, bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	desc, found := p.descriptions[uuid]
+	return desc, found, nil
+}
+
+// reload rereads the file if it has been modified since it was last read,
+// returning the UUIDs whose descriptions were added, changed or removed.
+func (p *fileDescriptionProvider) reload() ([]string, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	unchanged := info.ModTime().Equal(p.modTime)
+	p.mu.RUnlock()
+
+	if unchanged {
+		return nil, nil
+	}
+
+	descriptions, err := readDescriptions(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	changed := changedDescriptions(p.descriptions, descriptions)
+	p.descriptions = descriptions
+	p.modTime = info.ModTime()
+	p.mu.Unlock()
+
+	log.Printf("Loaded %d descriptions from %s", len(descriptions), p.path)
+	return changed, nil
+}
+
+// watch reloads the file every interval until the process exits, purging
+// the cached organisations whose descriptions changed. A file that fails to
+// load leaves the previous descriptions in place.
+func (p *fileDescriptionProvider) watch(interval time.Duration, cache *organisationCache) {
+	for range time.Tick(interval) {
+		changed, err := p.reload()
+		if err != nil {
+			log.Printf("Could not reload descriptions from %s, err=%s", p.path, err)
+			continue
+		}
+
+		for _, uuid := range changed {
+			n := cache.purge(uuid)
+			log.Printf("Purged %d cached entries for org %s after its description changed", n, uuid)
+		}
+	}
+}
+
+func changedDescriptions(old map[string]string, new map[string]string) []string {
+	changed := []string{}
+	for uuid, desc := range new {
+		if prev, found := old[uuid]; !found || prev != desc {
+			changed = append(changed, uuid)
+		}
+	}
+	for uuid := range old {
+		if _, found := new[uuid]; !found {
+			changed = append(changed, uuid)
+		}
+	}
+	return changed
+}
+
+// neoDescriptionProvider serves the description property of Organisation
+// nodes from the results of their organisation queries, which are run in
+// the same batch as the rest of each organisation's queries.
+type neoDescriptionProvider struct {
+	results []organisation
+}
+
+func (p neoDescriptionProvider) getDescription(uuid string) (string, bool, error) {
+	for _, result := range p.results {
+		if result.ID == uuid && result.Description != "" {
+			return result.Description, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// storedDescriptionProvider holds descriptions set through the admin API,
+// persisting them to a local JSON file.
+type storedDescriptionProvider struct {
+	path         string
+	mu           sync.RWMutex
+	descriptions map[string]string
+}
+
+func newStoredDescriptionProvider(path string) (*storedDescriptionProvider, error) {
+	p := &storedDescriptionProvider{path: path, descriptions: map[string]string{}}
+
+	descriptions, err := readDescriptions(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.descriptions = descriptions
+	return p, nil
+}
+
+func (p *storedDescriptionProvider) getDescription(uuid string) (string, bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	desc, found := p.descriptions[uuid]
+	return desc, found, nil
+}
+
+// setDescription stores desc for uuid, or removes the stored description if
+// desc is empty.
+func (p *storedDescriptionProvider) setDescription(uuid string, desc string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	descriptions := make(map[string]string, len(p.descriptions)+1)
+	for k, v := range p.descriptions {
+		descriptions[k] = v
+	}
+
+	if desc == "" {
+		delete(descriptions, uuid)
+	} else {
+		descriptions[uuid] = desc
+	}
+
+	if err := writeJSONFile(p.path, descriptions); err != nil {
+		return err
+	}
+
+	p.descriptions = descriptions
+	return nil
+}
+
+func readDescriptions(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	descriptions := map[string]string{}
+	if err := json.NewDecoder(f).Decode(&descriptions); err != nil {
+		return nil, err
+	}
+	return descriptions, nil
+}
+
+// writeJSONFile replaces the file at path with v encoded as JSON, writing to
+// a temporary file first so readers never see a partial file.
+func writeJSONFile(path string, v interface{}) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err == nil {
+		_, err = f.Write(b)
+	}
+
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
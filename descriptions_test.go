@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileDescriptionsReloadReportsChangedOrganisations(t *testing.T) {
+	path := t.TempDir() + "/descriptions.json"
+	write := func(body string, modTime time.Time) {
+		assert.NoError(t, ioutil.WriteFile(path, []byte(body), 0644))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	write(`{"a": "A", "b": "B", "c": "C"}`, start)
+
+	p, err := newFileDescriptionProvider(path)
+	assert.NoError(t, err)
+
+	changed, err := p.reload()
+	assert.NoError(t, err)
+	assert.Empty(t, changed, "unmodified file")
+
+	write(`{"a": "A", "b": "B2", "d": "D"}`, start.Add(time.Minute))
+
+	changed, err = p.reload()
+	assert.NoError(t, err)
+	sort.Strings(changed)
+	assert.Equal(t, []string{"b", "c", "d"}, changed)
+
+	desc, found, _ := p.getDescription("b")
+	assert.True(t, found)
+	assert.Equal(t, "B2", desc)
+
+	_, found, _ = p.getDescription("c")
+	assert.False(t, found)
+}
+
+func TestDescriptionsFallBackToTheGraph(t *testing.T) {
+	path := t.TempDir() + "/descriptions.json"
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"a": "From the file"}`), 0644))
+
+	file, err := newFileDescriptionProvider(path)
+	assert.NoError(t, err)
+
+	graph := neoDescriptionProvider{[]organisation{{ID: "a", Description: "A in the graph"}, {ID: "b", Description: "B in the graph"}, {ID: "c"}}}
+	descriptions := descriptionProviders{file, graph}
+
+	tests := []struct {
+		uuid  string
+		want  string
+		found bool
+	}{
+		{"a", "From the file", true},
+		{"b", "B in the graph", true},
+		{"c", "", false},
+		{"d", "", false},
+	}
+
+	for _, test := range tests {
+		desc, found, err := descriptions.getDescription(test.uuid)
+		assert.NoError(t, err)
+		assert.Equal(t, test.found, found, test.uuid)
+		assert.Equal(t, test.want, desc, test.uuid)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// enrichedContentClient fetches content from the FT Content API, through
+// a cache shared by every organisation. Each call is bounded by the client
+// timeout, so that a hung call can't hold one of the shared enrichment
+// workers indefinitely.
+type enrichedContentClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	cache      *contentCache
+}
+
+func newEnrichedContentClient(baseURL string, apiKey string, timeout time.Duration, cache *contentCache) *enrichedContentClient {
+	return &enrichedContentClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Transport: httpClient.Transport, Timeout: timeout},
+		cache:      cache,
+	}
+}
+
+func (c *enrichedContentClient) enrichedContentURL(uuid string) string {
+	return fmt.Sprintf("%s/enrichedcontent/%s", c.baseURL, uuid)
+}
+
+// contentURL maps an API URL, such as the ID of an image set or one of its
+// members, onto the configured base URL.
+func (c *enrichedContentClient) contentURL(id string) string {
+	return fmt.Sprintf("%s/content/%s", c.baseURL, id[strings.LastIndex(id, "/")+1:])
+}
+
+func (c *enrichedContentClient) get(reqURL string) (enrichedContent, error) {
+	return c.cache.get(reqURL, c.fetch)
+}
+
+// fetch requests reqURL, conditionally if etag is set.
+func (c *enrichedContentClient) fetch(reqURL string, etag string) (enrichedContent, string, bool, error) {
+	request, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return enrichedContent{}, "", false, err
+	}
+	request.Header.Set("X-Api-Key", c.apiKey)
+
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return enrichedContent{}, "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && etag != "" {
+		return enrichedContent{}, etag, true, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return enrichedContent{}, "", false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	enriched := enrichedContent{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&enriched); err != nil {
+		return enrichedContent{}, "", false, err
+	}
+
+	return enriched, resp.Header.Get("ETag"), false, nil
+}
+
+// workerPool runs jobs on a fixed number of goroutines. A single pool is
+// shared by every request so that the number of concurrent calls to the
+// Content API stays bounded however many organisations are being loaded.
+type workerPool struct {
+	jobs chan func()
+}
+
+func newWorkerPool(size int) *workerPool {
+	p := &workerPool{jobs: make(chan func())}
+
+	for i := 0; i < size; i++ {
+		go func() {
+			for job := range p.jobs {
+				job()
+			}
+		}()
+	}
+
+	return p
+}
+
+// run queues jobs and waits for them all to finish. Jobs must not
+// themselves call run, or they could wait forever for a free worker.
+func (p *workerPool) run(jobs []func()) {
+	var wg sync.WaitGroup
+	wg.Add(len(jobs))
+
+	for _, job := range jobs {
+		job := job
+		p.jobs <- func() {
+			defer wg.Done()
+			job()
+		}
+	}
+
+	wg.Wait()
+}
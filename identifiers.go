@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jmcvetta/neoism"
+)
+
+// identifierPatterns match an organisation o from an external identifier,
+// keyed by the authority name used in /organisations/by-identifier routes.
+var identifierPatterns = map[string]string{
+	"factset": `(:FactsetIdentifier {value:{value}})-[:IDENTIFIES]->(o:Organisation)`,
+	"lei":     `(:LegalEntityIdentifier {value:{value}})-[:IDENTIFIES]->(o:Organisation)`,
+	"figi":    `(:FIGIIdentifier {value:{value}})-[:IDENTIFIES]->(:FinancialInstrument)-[:ISSUED_BY]->(o:Organisation)`,
+	"upp":     `(:UPPIdentifier {value:{value}})-[:IDENTIFIES]->(o:Organisation)`,
+}
+
+type unknownAuthorityError struct {
+	authority string
+}
+
+func (e unknownAuthorityError) Error() string {
+	return fmt.Sprintf("unknown identifier authority %q", e.authority)
+}
+
+// resolveIdentifier finds the UUID of the organisation identified by value
+// under authority.
+func (ocs simpleOrganisationContentService) resolveIdentifier(authority string, value string) (string, bool, error) {
+	pattern, found := identifierPatterns[authority]
+
+	if !found {
+		return "", false, unknownAuthorityError{authority}
+	}
+
+	results := []struct {
+		UUID string
+	}{}
+
+	query := &neoism.CypherQuery{
+		Statement: fmt.Sprintf(`
+		MATCH %s
+		RETURN DISTINCT o.uuid as UUID`, pattern),
+		Parameters: neoism.Props{"value": value},
+		Result:     &results,
+	}
+
+	if err := ocs.conn.CypherBatch([]*neoism.CypherQuery{query}); err != nil {
+		return "", false, err
+	}
+
+	if len(results) == 0 {
+		log.Printf("No organisation found for %s identifier:%s", authority, value)
+		return "", false, nil
+	}
+
+	if len(results) > 1 {
+		log.Printf("%d organisations found for %s identifier:%s, using %s", len(results), authority, value, results[0].UUID)
+	}
+
+	return results[0].UUID, true, nil
+}
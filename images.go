@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// aspectTolerance is how far, as a fraction, a rendition's aspect ratio may
+// be from the requested one and still be considered a match.
+const aspectTolerance = 0.05
+
+// imageSpec describes the main image a caller wants. Zero values mean no
+// preference.
+type imageSpec struct {
+	width  int
+	aspect float64
+}
+
+// getImages fetches every rendition in the image set, ordered by width,
+// and picks the best of them for spec. Renditions that can't be fetched are
+// left out; it is only an error if none can be.
+func (ocs simpleOrganisationContentService) getImages(imageSetID string, spec imageSpec) ([]image, image, error) {
+	imageSet, err := ocs.contentAPI.get(ocs.contentAPI.contentURL(imageSetID))
+	if err != nil {
+		return nil, image{}, err
+	}
+
+	if len(imageSet.Members) == 0 {
+		return nil, image{}, fmt.Errorf("image set %s has no members", imageSetID)
+	}
+
+	images := []image{}
+	var memberErr error
+
+	for _, m := range imageSet.Members {
+		member, err := ocs.contentAPI.get(ocs.contentAPI.contentURL(m.ID))
+		if err != nil {
+			log.Printf("Skipping rendition %s of image set %s, err=%s", m.ID, imageSetID, err)
+			memberErr = err
+			continue
+		}
+
+		altText := member.Description
+		if altText == "" {
+			altText = imageSet.Description
+		}
+
+		images = append(images, image{
+			URL:     member.BinaryURL,
+			Width:   member.PixelWidth,
+			Height:  member.PixelHeight,
+			AltText: altText,
+		})
+	}
+
+	if len(images) == 0 {
+		return nil, image{}, fmt.Errorf("no renditions of image set %s could be fetched: %s", imageSetID, memberErr)
+	}
+
+	best := chooseImage(images, spec)
+
+	sort.SliceStable(images, func(i, j int) bool { return images[i].Width < images[j].Width })
+
+	return images, best, nil
+}
+
+// chooseImage picks the rendition closest to spec from images, which are
+// in image set order. Renditions of the wrong shape are only considered if
+// none has the requested aspect ratio; of the rest, the narrowest at least
+// as wide as requested is chosen, or failing that the widest. With no
+// preferences the first member of the set is used.
+func chooseImage(images []image, spec imageSpec) image {
+	candidates := images
+
+	if spec.aspect > 0 {
+		matching := []image{}
+		for _, img := range images {
+			if img.Height > 0 && math.Abs(float64(img.Width)/float64(img.Height)-spec.aspect)/spec.aspect <= aspectTolerance {
+				matching = append(matching, img)
+			}
+		}
+		if len(matching) > 0 {
+			candidates = matching
+		}
+	}
+
+	if spec.width <= 0 {
+		return candidates[0]
+	}
+
+	var best *image
+	for i := range candidates {
+		img := &candidates[i]
+		switch {
+		case best == nil:
+			best = img
+		case best.Width < spec.width:
+			if img.Width > best.Width {
+				best = img
+			}
+		case img.Width >= spec.width && img.Width < best.Width:
+			best = img
+		}
+	}
+
+	return *best
+}
+
+// parseAspect reads an aspect ratio written as 16:9 or 1.78.
+func parseAspect(v string) (float64, error) {
+	var aspect float64
+	var err error
+
+	if i := strings.Index(v, ":"); i >= 0 {
+		var w, h float64
+		if w, err = strconv.ParseFloat(v[:i], 64); err == nil {
+			if h, err = strconv.ParseFloat(v[i+1:], 64); err == nil && h != 0 {
+				aspect = w / h
+			}
+		}
+	} else {
+		aspect, err = strconv.ParseFloat(v, 64)
+	}
+
+	if err != nil || math.IsNaN(aspect) || aspect <= 0 || math.IsInf(aspect, 0) {
+		return 0, fmt.Errorf("%q is not an aspect ratio such as 16:9", v)
+	}
+	return aspect, nil
+}
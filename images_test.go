@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChooseImage(t *testing.T) {
+	images := []image{
+		{URL: "wide", Width: 2048, Height: 1152},
+		{URL: "square", Width: 600, Height: 600},
+		{URL: "small", Width: 320, Height: 180},
+		{URL: "medium", Width: 1024, Height: 576},
+	}
+
+	tests := []struct {
+		name string
+		spec imageSpec
+		want string
+	}{
+		{"no preference takes the first", imageSpec{}, "wide"},
+		{"narrowest at least as wide", imageSpec{width: 700}, "medium"},
+		{"exact width", imageSpec{width: 1024}, "medium"},
+		{"widest if none is wide enough", imageSpec{width: 4000}, "wide"},
+		{"aspect ratio first", imageSpec{width: 500, aspect: 1}, "square"},
+		{"aspect ratio within tolerance", imageSpec{width: 300, aspect: 1.8}, "small"},
+		{"aspect ratio alone takes the first match", imageSpec{aspect: 16.0 / 9}, "wide"},
+		{"wrong shapes if nothing matches", imageSpec{width: 900, aspect: 0.5}, "medium"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, chooseImage(images, test.spec).URL, test.name)
+	}
+}
+
+func TestParseAspect(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{in: "16:9", want: 16.0 / 9},
+		{in: "1:1", want: 1},
+		{in: "1.5", want: 1.5},
+		{in: "16:0", wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "-1.5", wantErr: true},
+		{in: "wide", wantErr: true},
+		{in: "16:", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "nan:1", wantErr: true},
+		{in: "Inf", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := parseAspect(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		assert.NoError(t, err, test.in)
+		assert.InDelta(t, test.want, got, 1e-9, test.in)
+	}
+}
+
+// newTestContentAPI serves content from docs by the last segment of the
+// request path, and a 500 for anything else.
+func newTestContentAPI(t *testing.T, docs map[string]enrichedContent) *enrichedContentClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, found := docs[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]]
+		if !found {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(server.Close)
+
+	return newEnrichedContentClient(server.URL, "key", time.Second, newContentCache(time.Minute, 100))
+}
+
+func TestGetImagesSkipsRenditionsThatFail(t *testing.T) {
+	ocs := simpleOrganisationContentService{contentAPI: newTestContentAPI(t, map[string]enrichedContent{
+		"set":   {Description: "Set alt", Members: []member{{ID: "http://api.ft.com/content/big"}, {ID: "http://api.ft.com/content/broken"}, {ID: "http://api.ft.com/content/small"}}},
+		"big":   {BinaryURL: "big.jpg", PixelWidth: 2048, PixelHeight: 1152},
+		"small": {BinaryURL: "small.jpg", PixelWidth: 640, PixelHeight: 360, Description: "Small alt"},
+	})}
+
+	images, best, err := ocs.getImages("http://api.ft.com/content/set", imageSpec{width: 600})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []image{
+		{URL: "small.jpg", Width: 640, Height: 360, AltText: "Small alt"},
+		{URL: "big.jpg", Width: 2048, Height: 1152, AltText: "Set alt"},
+	}, images)
+	assert.Equal(t, "small.jpg", best.URL)
+}
+
+func TestGetImagesFailsIfNoRenditionCanBeFetched(t *testing.T) {
+	ocs := simpleOrganisationContentService{contentAPI: newTestContentAPI(t, map[string]enrichedContent{
+		"set": {Members: []member{{ID: "a"}, {ID: "b"}}},
+	})}
+
+	_, _, err := ocs.getImages("set", imageSpec{})
+
+	assert.Error(t, err)
+}
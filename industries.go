@@ -0,0 +1,107 @@
+package main
+
+import (
+	"time"
+
+	"github.com/jmcvetta/neoism"
+)
+
+// industry is an industry classification and the organisations in it, most
+// written about first.
+type industry struct {
+	ID            string    `json:"id"`
+	PrefLabel     string    `json:"prefLabel"`
+	Organisations []concept `json:"organisations"`
+}
+
+// industryContent is the recent content mentioning any organisation in an
+// industry classification, each story tagged with the organisations it
+// mentions.
+type industryContent struct {
+	ID        string    `json:"id"`
+	PrefLabel string    `json:"prefLabel"`
+	Stories   []content `json:"stories"`
+	Next      string    `json:"next,omitempty"`
+	Warnings  []warning `json:"warnings,omitempty"`
+}
+
+func industryQuery(uuid string, result *[]industry) *neoism.CypherQuery {
+	return &neoism.CypherQuery{
+		Statement: `
+		MATCH (i:IndustryClassification {uuid:{uuid}})
+		OPTIONAL MATCH (i)--(o:Organisation)
+		WITH i, o, size((:Content)-[:MENTIONS]->(o)) as annCount
+		ORDER BY annCount DESC, o.prefLabel ASC
+		WITH i, [m IN collect({ID:o.uuid, PrefLabel:o.prefLabel, AnnCount:annCount}) WHERE m.ID IS NOT NULL] as organisations
+		RETURN i.uuid as ID, i.prefLabel as PrefLabel, organisations as Organisations`,
+		Parameters: neoism.Props{"uuid": uuid},
+		Result:     result,
+	}
+}
+
+// industryContentQuery is the industry section's query starting from the
+// classification rather than one of its organisations. Blocked stories are
+// left out before the page is cut, so that pages stay full.
+func industryContentQuery(uuid string, window timeWindow, p page, blocked []string, result *[]industryContent) *neoism.CypherQuery {
+	props := window.pageProps(uuid, p)
+	props["blocked"] = blocked
+
+	return &neoism.CypherQuery{
+		Statement: `
+		MATCH (i:IndustryClassification {uuid:{uuid}})
+		OPTIONAL MATCH (i)--(comp:Organisation)-[:MENTIONS]-(c:Content)
+		WHERE c.publishedDateEpoch > {secondsSinceEpoch} AND c.publishedDateEpoch <= {untilSecondsSinceEpoch} AND NOT c.uuid IN {blocked}
+		WITH i, c, collect({ID:comp.uuid, Label:comp.prefLabel}) as Tags
+		ORDER BY c.publishedDateEpoch DESC
+		WITH i, [s IN collect({Title:c.title, ID:c.uuid, PublishedDate:c.publishedDate, Tags:Tags}) WHERE s.ID IS NOT NULL] as stories
+		RETURN i.uuid as ID, i.prefLabel as PrefLabel, stories[{skip}..{skip}+{limit}] as Stories`,
+		Parameters: props,
+		Result:     result,
+	}
+}
+
+func (ocs simpleOrganisationContentService) getIndustry(uuid string) (industry, bool, error) {
+	results := []industry{}
+
+	if err := ocs.conn.CypherBatch([]*neoism.CypherQuery{industryQuery(uuid, &results)}); err != nil {
+		return industry{}, false, err
+	}
+
+	if len(results) == 0 {
+		return industry{}, false, nil
+	}
+
+	return results[0], true, nil
+}
+
+// getIndustryContent returns a page of the industry's recent stories,
+// enriched as an organisation's are and with editorial corrections and
+// blocks applied. The page is set by the options for the stories section.
+func (ocs simpleOrganisationContentService) getIndustryContent(uuid string, opts queryOptions) (industryContent, bool, error) {
+	results := []industryContent{}
+	p := opts.page(storiesSection)
+
+	if err := ocs.conn.CypherBatch([]*neoism.CypherQuery{industryContentQuery(uuid, opts.bounds(time.Now()), p, ocs.overrides.blockedStories(), &results)}); err != nil {
+		return industryContent{}, false, err
+	}
+
+	if len(results) == 0 {
+		return industryContent{}, false, nil
+	}
+
+	ic := results[0]
+
+	if len(ic.Stories) > p.limit {
+		ic.Stories = ic.Stories[:p.limit]
+		ic.Next = opts.nextLink("/industries/"+uuid+"/content", storiesSection)
+	}
+
+	ic.Stories = ocs.enrichContentList(&ic.Warnings, storiesSection, ic.Stories, opts.image)
+	ic.Stories = ocs.correctStories(ic.Stories)
+
+	if ic.Stories == nil {
+		ic.Stories = []content{}
+	}
+
+	return ic, true, nil
+}
@@ -1,27 +1,123 @@
 package main
 
 type content struct {
-	ID            string `json:"id"`
-	Title         string `json:"title"`
-	Standfirst    string `json:"standfirst"`
-	Byline        string `json:"byline"`
-	PublishedDate string `json:"publishedDate"`
-	ImageURL      string `json:"image"`
-	Tags          []tag  `json:"tags"`
+	ID            string  `json:"id"`
+	Title         string  `json:"title"`
+	Standfirst    string  `json:"standfirst"`
+	Byline        string  `json:"byline"`
+	PublishedDate string  `json:"publishedDate"`
+	ImageURL      string  `json:"image"`
+	Images        []image `json:"images,omitempty"`
+	Tags          []tag   `json:"tags"`
+
+	Standout       *standout       `json:"standout,omitempty"`
+	Predicates     []string        `json:"predicates,omitempty"`
+	Relevance      float64         `json:"relevance,omitempty"`
+	Recommendation *recommendation `json:"recommendation,omitempty"`
+}
+
+// recommendation is why the recommended reads API suggested a story, only
+// returned when explanations are requested.
+type recommendation struct {
+	Score       float64     `json:"score"`
+	Popularity  int         `json:"popularity"`
+	Published   string      `json:"published,omitempty"`
+	Explanation interface{} `json:"explanation,omitempty"`
 }
 type organisation struct {
-	ID                      string    `json:"id"`
-	Title                   string    `json:"title"`
-	Description             string    `json:"description"`
-	IndustryClassification  string    `json:"industryClassification"`
-	Stories                 []content `json:"stories"`
-	SubsidStories           []content `json:"subsidiaryStories"`
-	IndClassStories         []content `json:"industryClassificationStories"`
-	RecommendedReadsStories []content `json:"recommendedReadsStories"`
+	ID                      string            `json:"id"`
+	Title                   string            `json:"title"`
+	Description             string            `json:"description"`
+	IndustryClassification  string            `json:"industryClassification"`
+	Profile                 *profile          `json:"profile,omitempty"`
+	Stories                 []content         `json:"stories"`
+	ParentStories           []content         `json:"parentStories"`
+	SubsidStories           []content         `json:"subsidiaryStories"`
+	SiblingStories          []content         `json:"siblingStories"`
+	PeopleStories           []content         `json:"peopleStories"`
+	IndClassStories         []content         `json:"industryClassificationStories"`
+	RecommendedReadsStories []content         `json:"recommendedReadsStories"`
+	Next                    map[string]string `json:"next,omitempty"`
+	Warnings                []warning         `json:"warnings,omitempty"`
+}
+
+// section returns the stories of the named section, or nil if there is no
+// such section.
+func (org *organisation) section(name string) *[]content {
+	switch name {
+	case storiesSection:
+		return &org.Stories
+	case parentsSection:
+		return &org.ParentStories
+	case subsidiariesSection:
+		return &org.SubsidStories
+	case siblingsSection:
+		return &org.SiblingStories
+	case peopleSection:
+		return &org.PeopleStories
+	case industrySection:
+		return &org.IndClassStories
+	case recommendedSection:
+		return &org.RecommendedReadsStories
+	}
+	return nil
+}
+
+// warning names a section, and the story within it if there is one, that
+// is missing or incomplete in the response.
+type warning struct {
+	Section string `json:"section"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// profile holds the company facts for an organisation, as served by the
+// public organisations API.
+type profile struct {
+	Aliases                []string             `json:"aliases,omitempty"`
+	LegalEntityIdentifier  string               `json:"legalEntityIdentifier,omitempty"`
+	IndustryClassification *concept             `json:"industryClassification,omitempty"`
+	Parent                 *concept             `json:"parentOrganisation,omitempty"`
+	Subsidiaries           []concept            `json:"subsidiaries,omitempty"`
+	FinancialInstrument    *financialInstrument `json:"financialInstrument,omitempty"`
+	Memberships            []membership         `json:"memberships,omitempty"`
 }
+
+type concept struct {
+	ID        string `json:"id"`
+	PrefLabel string `json:"prefLabel"`
+	AnnCount  int    `json:"annotationsCount,omitempty"`
+}
+
+type financialInstrument struct {
+	ID        string `json:"id"`
+	PrefLabel string `json:"prefLabel"`
+	FIGI      string `json:"figi"`
+}
+
+type membership struct {
+	ID              string  `json:"id"`
+	PrefLabel       string  `json:"prefLabel"`
+	InceptionDate   string  `json:"inceptionDate,omitempty"`
+	TerminationDate string  `json:"terminationDate,omitempty"`
+	AnnCount        int     `json:"annotationsCount,omitempty"`
+	Person          concept `json:"person"`
+}
+
+// image is a rendition of a story's main image.
+type image struct {
+	URL     string `json:"url"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	AltText string `json:"altText,omitempty"`
+}
+
 type tag struct {
-	URL   string `json:"url"`
-	Label string `json:"label"`
+	ID       string `json:"id,omitempty"`
+	URL      string `json:"url"`
+	Label    string `json:"label"`
+	Role     string `json:"role,omitempty"`
+	Priority int    `json:"priority,omitempty"`
 }
 
 // rec recReadsURL
@@ -38,27 +134,21 @@ type article struct {
 	Published  string  `json:"published"`
 	Score      float64 `json:"score"`
 	Title      string  `json:"title"`
+
+	Explanation interface{} `json:"explanation"`
 }
 
 type enrichedContent struct {
 	AlternativeTitles struct {
 		PromotionalTitle string `json:"promotionalTitle"`
 	} `json:"alternativeTitles"`
-	Annotations []struct {
-		APIURL     string   `json:"apiUrl"`
-		DirectType string   `json:"directType"`
-		ID         string   `json:"id"`
-		LeiCode    string   `json:"leiCode"`
-		Predicate  string   `json:"predicate"`
-		PrefLabel  string   `json:"prefLabel"`
-		Type       string   `json:"type"`
-		Types      []string `json:"types"`
-	} `json:"annotations"`
-	APIURL          string   `json:"apiUrl"`
-	BodyXML         string   `json:"bodyXML"`
-	Brands          []string `json:"brands"`
-	Byline          string   `json:"byline"`
-	CanBeSyndicated string   `json:"canBeSyndicated"`
+	Annotations     []annotation `json:"annotations"`
+	APIURL          string       `json:"apiUrl"`
+	BodyXML         string       `json:"bodyXML"`
+	Brands          []string     `json:"brands"`
+	Byline          string       `json:"byline"`
+	CanBeSyndicated string       `json:"canBeSyndicated"`
+	Description     string       `json:"description"`
 	Comments        struct {
 		Enabled bool `json:"enabled"`
 	} `json:"comments"`
@@ -66,18 +156,33 @@ type enrichedContent struct {
 	MainImage     mainImage `json:"mainImage"`
 	Members       []member  `json:"members"`
 	BinaryURL     string    `json:"binaryUrl"`
+	PixelHeight   int       `json:"pixelHeight"`
+	PixelWidth    int       `json:"pixelWidth"`
 	PrefLabel     string    `json:"prefLabel"`
 	PublishedDate string    `json:"publishedDate"`
 	RequestURL    string    `json:"requestUrl"`
 	Standfirst    string    `json:"standfirst"`
-	Standout      struct {
-		EditorsChoice bool `json:"editorsChoice"`
-		Exclusive     bool `json:"exclusive"`
-		Scoop         bool `json:"scoop"`
-	} `json:"standout"`
-	Title  string   `json:"title"`
-	Types  []string `json:"types"`
-	WebURL string   `json:"webUrl"`
+	Standout      standout  `json:"standout"`
+	Title         string    `json:"title"`
+	Types         []string  `json:"types"`
+	WebURL        string    `json:"webUrl"`
+}
+
+type annotation struct {
+	APIURL     string   `json:"apiUrl"`
+	DirectType string   `json:"directType"`
+	ID         string   `json:"id"`
+	LeiCode    string   `json:"leiCode"`
+	Predicate  string   `json:"predicate"`
+	PrefLabel  string   `json:"prefLabel"`
+	Type       string   `json:"type"`
+	Types      []string `json:"types"`
+}
+
+type standout struct {
+	EditorsChoice bool `json:"editorsChoice"`
+	Exclusive     bool `json:"exclusive"`
+	Scoop         bool `json:"scoop"`
 }
 
 type member struct {
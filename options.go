@@ -0,0 +1,423 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmcvetta/neoism"
+)
+
+const (
+	defaultWindow = 90 * 24 * time.Hour
+	maxWindowDays = 3650
+	defaultLimit  = 5
+	maxLimit      = 100
+)
+
+// maxSectionStories is how many of each section's stories are fetched,
+// deduplicated across sections and then paged through. Every page is cut
+// from the same stories, so pages neither repeat nor skip any.
+const maxSectionStories = 200
+
+// The story sections of an organisation, as named in query parameters.
+const (
+	storiesSection      = "stories"
+	parentsSection      = "parents"
+	subsidiariesSection = "subsidiaries"
+	siblingsSection     = "siblings"
+	peopleSection       = "people"
+	industrySection     = "industry"
+	recommendedSection  = "recommended"
+)
+
+var sections = []string{
+	storiesSection,
+	parentsSection,
+	subsidiariesSection,
+	siblingsSection,
+	peopleSection,
+	industrySection,
+	recommendedSection,
+}
+
+// queryOptions are the caller-controlled settings for an organisation
+// request. The time window is either absolute (from) or relative to the end
+// of the window (window); to defaults to the time of the request.
+type queryOptions struct {
+	from    time.Time
+	to      time.Time
+	window  time.Duration
+	pages   map[string]page
+	include map[string]bool
+	explain bool
+	image   imageSpec
+	sort    string
+}
+
+// page selects a slice of a section's stories.
+type page struct {
+	limit  int
+	offset int
+}
+
+// timeWindow is a resolved time window in seconds since the epoch, as
+// compared against publishedDateEpoch in the Cypher queries.
+type timeWindow struct {
+	since int64
+	until int64
+}
+
+func defaultQueryOptions() queryOptions {
+	return queryOptions{window: defaultWindow, pages: map[string]page{}, sort: sortRecent}
+}
+
+// parseQueryOptions reads ?from=, ?to= and ?window= from the request. Times
+// are RFC3339 timestamps or YYYY-MM-DD dates; windows are Go durations or a
+// number of days or weeks such as 30d or 2w. Each section can also be paged
+// with ?<section>.limit= and ?<section>.offset=, and ?include= restricts the
+// response to a comma-separated list of sections. ?explain=true adds the
+// recommender's scores and reasoning to recommended reads, and ?imageWidth=
+// and ?imageAspect= select the main image rendition. ?sort=relevance ranks
+// the organisation's own stories rather than listing the most recent.
+func parseQueryOptions(req *http.Request) (queryOptions, error) {
+	opts := defaultQueryOptions()
+	params := req.URL.Query()
+
+	var err error
+
+	if v := params.Get("to"); v != "" {
+		if opts.to, err = parseTime(v); err != nil {
+			return queryOptions{}, fmt.Errorf("invalid to: %s", err)
+		}
+	}
+
+	from, window := params.Get("from"), params.Get("window")
+
+	if from != "" && window != "" {
+		return queryOptions{}, errors.New("from and window cannot both be set")
+	}
+
+	if from != "" {
+		if opts.from, err = parseTime(from); err != nil {
+			return queryOptions{}, fmt.Errorf("invalid from: %s", err)
+		}
+		opts.window = 0
+	}
+
+	if window != "" {
+		if opts.window, err = parseWindow(window); err != nil {
+			return queryOptions{}, fmt.Errorf("invalid window: %s", err)
+		}
+	}
+
+	if !opts.from.IsZero() {
+		to := opts.to
+		if to.IsZero() {
+			to = time.Now()
+		}
+
+		switch {
+		case !opts.to.IsZero() && !opts.from.Before(opts.to):
+			return queryOptions{}, errors.New("from must be before to")
+		case opts.from.After(to):
+			return queryOptions{}, errors.New("from cannot be in the future unless to is set")
+		case to.Sub(opts.from) > maxWindowDays*24*time.Hour:
+			return queryOptions{}, fmt.Errorf("from and to must be at most %d days apart", maxWindowDays)
+		}
+	}
+
+	if v := params.Get("sort"); v != "" {
+		if v != sortRecent && v != sortRelevance {
+			return queryOptions{}, errors.New("invalid sort: must be recent or relevance")
+		}
+		opts.sort = v
+	}
+
+	for _, section := range sections {
+		p := page{limit: defaultLimit}
+
+		if v := params.Get(section + ".limit"); v != "" {
+			if p.limit, err = strconv.Atoi(v); err != nil || p.limit < 1 || p.limit > maxLimit {
+				return queryOptions{}, fmt.Errorf("invalid %s.limit: must be between 1 and %d", section, maxLimit)
+			}
+		}
+
+		if v := params.Get(section + ".offset"); v != "" {
+			if p.offset, err = strconv.Atoi(v); err != nil || p.offset < 0 || p.offset >= opts.sectionStories(section) {
+				return queryOptions{}, fmt.Errorf("invalid %s.offset: must be between 0 and %d", section, opts.sectionStories(section)-1)
+			}
+		}
+
+		opts.pages[section] = p
+	}
+
+	if v := params.Get("include"); v != "" {
+		opts.include = map[string]bool{}
+
+		for _, section := range strings.Split(v, ",") {
+			section = strings.TrimSpace(section)
+			if !isSection(section) {
+				return queryOptions{}, fmt.Errorf("invalid include: unknown section %q", section)
+			}
+			opts.include[section] = true
+		}
+	}
+
+	if v := params.Get("explain"); v != "" {
+		if opts.explain, err = strconv.ParseBool(v); err != nil {
+			return queryOptions{}, errors.New("invalid explain: must be true or false")
+		}
+	}
+
+	if v := params.Get("imageWidth"); v != "" {
+		if opts.image.width, err = strconv.Atoi(v); err != nil || opts.image.width < 1 {
+			return queryOptions{}, errors.New("invalid imageWidth: must be a positive integer")
+		}
+	}
+
+	if v := params.Get("imageAspect"); v != "" {
+		if opts.image.aspect, err = parseAspect(v); err != nil {
+			return queryOptions{}, fmt.Errorf("invalid imageAspect: %s", err)
+		}
+	}
+
+	return opts, nil
+}
+
+// bounds resolves the options into a time window ending at now unless an
+// explicit end was requested.
+func (opts queryOptions) bounds(now time.Time) timeWindow {
+	to := opts.to
+	if to.IsZero() {
+		to = now
+	}
+
+	from := opts.from
+	if from.IsZero() {
+		from = to.Add(-opts.window)
+	}
+
+	return timeWindow{since: from.Unix(), until: to.Unix()}
+}
+
+// includes reports whether section was requested. All sections are
+// included unless ?include= was given.
+func (opts queryOptions) includes(section string) bool {
+	return opts.include == nil || opts.include[section]
+}
+
+// sectionStories is how many of section's stories can be paged through:
+// fewer for the organisation's own stories when they are ranked by
+// relevance.
+func (opts queryOptions) sectionStories(section string) int {
+	if section == storiesSection && opts.sort == sortRelevance {
+		return maxRelevanceCandidates
+	}
+	return maxSectionStories
+}
+
+func (opts queryOptions) page(section string) page {
+	if p, found := opts.pages[section]; found {
+		return p
+	}
+	return page{limit: defaultLimit}
+}
+
+// values encodes the options as query parameters, leaving out defaults.
+// Relative windows are encoded by length rather than resolved times, so
+// that requests for "the last 30 days" share a cache entry until it expires.
+func (opts queryOptions) values() url.Values {
+	v := url.Values{}
+
+	if !opts.from.IsZero() {
+		v.Set("from", opts.from.Format(time.RFC3339))
+	} else if opts.window != defaultWindow {
+		v.Set("window", formatWindow(opts.window))
+	}
+
+	if !opts.to.IsZero() {
+		v.Set("to", opts.to.Format(time.RFC3339))
+	}
+
+	if opts.include != nil {
+		included := []string{}
+		for _, section := range sections {
+			if opts.include[section] {
+				included = append(included, section)
+			}
+		}
+		v.Set("include", strings.Join(included, ","))
+	}
+
+	if opts.explain {
+		v.Set("explain", "true")
+	}
+
+	if opts.sort != sortRecent {
+		v.Set("sort", opts.sort)
+	}
+
+	if opts.image.width > 0 {
+		v.Set("imageWidth", strconv.Itoa(opts.image.width))
+	}
+
+	if opts.image.aspect > 0 {
+		v.Set("imageAspect", strconv.FormatFloat(opts.image.aspect, 'g', -1, 64))
+	}
+
+	for section, p := range opts.pages {
+		if !opts.includes(section) {
+			continue
+		}
+		if p.limit != defaultLimit {
+			v.Set(section+".limit", strconv.Itoa(p.limit))
+		}
+		if p.offset != 0 {
+			v.Set(section+".offset", strconv.Itoa(p.offset))
+		}
+	}
+
+	return v
+}
+
+// cacheKey identifies the cached payload for uuid under these options.
+func (opts queryOptions) cacheKey(uuid string) string {
+	return uuid + "?" + opts.values().Encode()
+}
+
+// nextLink is the request to path for the page of section following the
+// current one.
+func (opts queryOptions) nextLink(path string, section string) string {
+	p := opts.page(section)
+
+	v := opts.values()
+	v.Set(section+".limit", strconv.Itoa(p.limit))
+	v.Set(section+".offset", strconv.Itoa(p.offset+p.limit))
+
+	return path + "?" + v.Encode()
+}
+
+// pageOf is the requested page of section from all of its stories.
+func (opts queryOptions) pageOf(section string, stories []content) []content {
+	p := opts.page(section)
+
+	if p.offset >= len(stories) {
+		return nil
+	}
+
+	end := p.offset + p.limit
+	if end > opts.sectionStories(section) {
+		end = opts.sectionStories(section)
+	}
+	if end > len(stories) {
+		end = len(stories)
+	}
+
+	return stories[p.offset:end]
+}
+
+// paginate cuts the requested page of section from all of its fetched
+// stories. If there are stories after it, a link to the next page is
+// recorded on org. Only the first opts.sectionStories(section) stories can
+// be paged through, so there is no link to a page starting after them, even
+// if the time window holds more.
+func (opts queryOptions) paginate(org *organisation, section string, stories []content) []content {
+	p := opts.page(section)
+
+	if end := p.offset + p.limit; len(stories) > end && end < opts.sectionStories(section) {
+		if org.Next == nil {
+			org.Next = map[string]string{}
+		}
+		org.Next[section] = opts.nextLink("/organisations/"+org.ID, section)
+	}
+
+	return opts.pageOf(section, stories)
+}
+
+func (w timeWindow) props(uuid string) neoism.Props {
+	return neoism.Props{"uuid": uuid, "secondsSinceEpoch": w.since, "untilSecondsSinceEpoch": w.until}
+}
+
+// sectionProps adds the number of stories fetched for a section to the
+// query parameters for w. Sections are paged after deduplication rather
+// than in the query, so the same stories are fetched for every page. One
+// more is requested, to tell whether there is a page after the last.
+func (w timeWindow) sectionProps(uuid string) neoism.Props {
+	props := w.props(uuid)
+	props["limit"] = maxSectionStories + 1
+	return props
+}
+
+// pageProps adds the bounds of p to the query parameters for w. One more
+// story than the page holds is requested, to tell whether there is another.
+func (w timeWindow) pageProps(uuid string, p page) neoism.Props {
+	props := w.props(uuid)
+	props["skip"] = p.offset
+	props["limit"] = p.limit + 1
+	return props
+}
+
+func isSection(name string) bool {
+	for _, section := range sections {
+		if section == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+func parseWindow(v string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+
+	switch {
+	case strings.HasSuffix(v, "d"):
+		d, err = parseDays(strings.TrimSuffix(v, "d"), 1)
+	case strings.HasSuffix(v, "w"):
+		d, err = parseDays(strings.TrimSuffix(v, "w"), 7)
+	default:
+		d, err = time.ParseDuration(v)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	if d > maxWindowDays*24*time.Hour {
+		return 0, fmt.Errorf("must be at most %d days", maxWindowDays)
+	}
+	return d, nil
+}
+
+func parseDays(v string, multiplier int) (time.Duration, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, err
+	}
+	// Checked before multiplying, so that large values can't overflow.
+	if n > maxWindowDays/multiplier {
+		return 0, fmt.Errorf("must be at most %d days", maxWindowDays)
+	}
+	return time.Duration(n*multiplier) * 24 * time.Hour, nil
+}
+
+func formatWindow(d time.Duration) string {
+	if day := 24 * time.Hour; d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
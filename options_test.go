@@ -0,0 +1,202 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "12h", want: 12 * time.Hour},
+		{in: "3650d", want: 3650 * 24 * time.Hour},
+		{in: "3651d", wantErr: true},
+		{in: "522w", wantErr: true},
+		{in: "200000d", wantErr: true},
+		{in: "9223372036854775807d", wantErr: true},
+		{in: "90000h", wantErr: true},
+		{in: "0d", wantErr: true},
+		{in: "-1d", wantErr: true},
+		{in: "xd", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := parseWindow(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestParseQueryOptions(t *testing.T) {
+	daysAgo := func(n int) string { return time.Now().AddDate(0, 0, -n).Format("2006-01-02") }
+
+	tests := []struct {
+		query   string
+		wantErr bool
+		check   func(queryOptions)
+	}{
+		{query: "", check: func(opts queryOptions) {
+			assert.Equal(t, defaultWindow, opts.window)
+			assert.Equal(t, page{limit: defaultLimit}, opts.page(storiesSection))
+			assert.True(t, opts.includes(industrySection))
+			assert.Equal(t, sortRecent, opts.sort)
+		}},
+		{query: "from=2017-01-01&to=2017-02-01T12:00:00Z", check: func(opts queryOptions) {
+			w := opts.bounds(time.Now())
+			assert.Equal(t, time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC).Unix(), w.since)
+			assert.Equal(t, time.Date(2017, 2, 1, 12, 0, 0, 0, time.UTC).Unix(), w.until)
+		}},
+		{query: "window=2w&to=2017-02-01", check: func(opts queryOptions) {
+			w := opts.bounds(time.Now())
+			assert.Equal(t, time.Date(2017, 1, 18, 0, 0, 0, 0, time.UTC).Unix(), w.since)
+		}},
+		{query: "stories.limit=20&industry.offset=10&include=stories,%20industry", check: func(opts queryOptions) {
+			assert.Equal(t, page{limit: 20}, opts.page(storiesSection))
+			assert.Equal(t, page{limit: defaultLimit, offset: 10}, opts.page(industrySection))
+			assert.True(t, opts.includes(industrySection))
+			assert.False(t, opts.includes(peopleSection))
+		}},
+		{query: "sort=relevance&explain=true&imageWidth=640&imageAspect=16:9", check: func(opts queryOptions) {
+			assert.Equal(t, sortRelevance, opts.sort)
+			assert.True(t, opts.explain)
+			assert.Equal(t, 640, opts.image.width)
+			assert.InDelta(t, 16.0/9, opts.image.aspect, 1e-9)
+		}},
+		{query: "from=" + daysAgo(30), check: func(opts queryOptions) {
+			assert.Equal(t, time.Duration(0), opts.window)
+			assert.True(t, opts.to.IsZero())
+		}},
+		{query: "from=2000-01-01&to=2009-06-01", check: func(opts queryOptions) {
+			assert.Equal(t, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), opts.from)
+		}},
+		{query: "from=1900-01-01", wantErr: true},
+		{query: "from=" + daysAgo(maxWindowDays+1), wantErr: true},
+		{query: "from=2000-01-01&to=2010-06-01", wantErr: true},
+		{query: "from=" + daysAgo(-2), wantErr: true},
+		{query: "from=" + daysAgo(-2) + "&to=" + daysAgo(-30), check: func(opts queryOptions) {
+			assert.True(t, opts.from.Before(opts.to))
+		}},
+		{query: "from=2017-01-01&window=30d", wantErr: true},
+		{query: "from=2017-02-01&to=2017-01-01", wantErr: true},
+		{query: "window=200000d", wantErr: true},
+		{query: "to=yesterday", wantErr: true},
+		{query: "stories.limit=0", wantErr: true},
+		{query: "stories.limit=101", wantErr: true},
+		{query: "people.offset=-1", wantErr: true},
+		{query: "people.offset=200", wantErr: true},
+		{query: "people.offset=199", check: func(opts queryOptions) {
+			assert.Equal(t, 199, opts.page(peopleSection).offset)
+		}},
+		{query: "sort=relevance&stories.offset=50", wantErr: true},
+		{query: "sort=relevance&stories.offset=49&people.offset=100", check: func(opts queryOptions) {
+			assert.Equal(t, 49, opts.page(storiesSection).offset)
+			assert.Equal(t, 100, opts.page(peopleSection).offset)
+		}},
+		{query: "include=stories,board", wantErr: true},
+		{query: "explain=maybe", wantErr: true},
+		{query: "imageWidth=0", wantErr: true},
+		{query: "sort=popular", wantErr: true},
+	}
+
+	for _, test := range tests {
+		opts, err := parseQueryOptions(httptest.NewRequest("GET", "/organisations/x?"+test.query, nil))
+		if test.wantErr {
+			assert.Error(t, err, test.query)
+			continue
+		}
+		if assert.NoError(t, err, test.query) {
+			test.check(opts)
+		}
+	}
+}
+
+func TestCacheKeyLeavesOutDefaults(t *testing.T) {
+	parse := func(query string) queryOptions {
+		opts, err := parseQueryOptions(httptest.NewRequest("GET", "/organisations/x?"+query, nil))
+		assert.NoError(t, err)
+		return opts
+	}
+
+	assert.Equal(t, "x?", parse("").cacheKey("x"))
+	assert.Equal(t, parse("").cacheKey("x"), parse("window=90d&stories.limit=5&sort=recent").cacheKey("x"))
+	assert.Equal(t, "x?window=30d", parse("window=720h").cacheKey("x"))
+	assert.Equal(t, "x?include=stories&stories.offset=5", parse("include=stories&stories.offset=5&people.offset=5").cacheKey("x"))
+}
+
+func TestPaginate(t *testing.T) {
+	stories := make([]content, 12)
+	for i := range stories {
+		stories[i].ID = string(rune('a' + i))
+	}
+
+	tests := []struct {
+		p        page
+		stories  int
+		want     []string
+		wantNext string
+	}{
+		{p: page{limit: 5}, stories: 12, want: []string{"a", "b", "c", "d", "e"}, wantNext: "/organisations/org?stories.limit=5&stories.offset=5"},
+		{p: page{limit: 5, offset: 10}, stories: 12, want: []string{"k", "l"}},
+		{p: page{limit: 5, offset: 5}, stories: 10, want: []string{"f", "g", "h", "i", "j"}},
+		{p: page{limit: 5, offset: 20}, stories: 12, want: []string{}},
+	}
+
+	for _, test := range tests {
+		opts := defaultQueryOptions()
+		opts.pages[storiesSection] = test.p
+
+		org := organisation{ID: "org"}
+		got := opts.paginate(&org, storiesSection, stories[:test.stories])
+
+		assert.Equal(t, test.want, storyIDs(got), "%+v", test.p)
+		assert.Equal(t, test.wantNext, org.Next[storiesSection], "%+v", test.p)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	opts := defaultQueryOptions()
+	opts.pages[peopleSection] = page{limit: 3, offset: 6}
+
+	assert.Equal(t, "/organisations/org?people.limit=3&people.offset=9", opts.nextLink("/organisations/org", peopleSection))
+	assert.Equal(t, "/industries/ind/content?people.limit=3&people.offset=9", opts.nextLink("/industries/ind/content", peopleSection))
+}
+
+func TestPaginateStopsAtMaxSectionStories(t *testing.T) {
+	stories := make([]content, maxSectionStories+1)
+
+	opts := defaultQueryOptions()
+	opts.pages[storiesSection] = page{limit: 30, offset: 180}
+
+	org := organisation{ID: "org"}
+	got := opts.paginate(&org, storiesSection, stories)
+
+	assert.Len(t, got, 20)
+	assert.Empty(t, org.Next)
+}
+
+func TestPaginateStopsAtMaxRelevanceCandidates(t *testing.T) {
+	stories := make([]content, maxRelevanceCandidates+1)
+
+	opts := defaultQueryOptions()
+	opts.sort = sortRelevance
+	opts.pages[storiesSection] = page{limit: 20, offset: 40}
+
+	org := organisation{ID: "org"}
+	got := opts.paginate(&org, storiesSection, stories)
+
+	assert.Len(t, got, 10)
+	assert.Empty(t, org.Next)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// editorialOverrides are editors' corrections to the generated sections,
+// applied after every section has been assembled.
+type editorialOverrides struct {
+	Stories       map[string]storyOverride        `json:"stories"`
+	Organisations map[string]organisationOverride `json:"organisations"`
+}
+
+// storyOverride corrects a story wherever it appears.
+type storyOverride struct {
+	Title    string `json:"title,omitempty"`
+	ImageURL string `json:"image,omitempty"`
+	Blocked  bool   `json:"blocked,omitempty"`
+}
+
+// organisationOverride adjusts the sections of one organisation. Pinned
+// maps a section name to the stories, in order, shown at the top of it.
+type organisationOverride struct {
+	Pinned  map[string][]string `json:"pinned,omitempty"`
+	Blocked []string            `json:"blocked,omitempty"`
+}
+
+// overridesStore holds the editorial overrides, persisting them to a local
+// JSON file.
+type overridesStore struct {
+	path      string
+	mu        sync.RWMutex
+	overrides editorialOverrides
+}
+
+func newOverridesStore(path string) (*overridesStore, error) {
+	s := &overridesStore{path: path, overrides: editorialOverrides{
+		Stories:       map[string]storyOverride{},
+		Organisations: map[string]organisationOverride{},
+	}}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&s.overrides); err != nil {
+		return nil, err
+	}
+
+	if s.overrides.Stories == nil {
+		s.overrides.Stories = map[string]storyOverride{}
+	}
+	if s.overrides.Organisations == nil {
+		s.overrides.Organisations = map[string]organisationOverride{}
+	}
+
+	return s, nil
+}
+
+func (s *overridesStore) all() editorialOverrides {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.overrides
+}
+
+func (s *overridesStore) story(id string) (storyOverride, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, found := s.overrides.Stories[id]
+	return o, found
+}
+
+// blockedStories are the IDs of the stories blocked everywhere.
+func (s *overridesStore) blockedStories() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blocked := []string{}
+	for id, o := range s.overrides.Stories {
+		if o.Blocked {
+			blocked = append(blocked, id)
+		}
+	}
+	return blocked
+}
+
+func (s *overridesStore) organisation(uuid string) (organisationOverride, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, found := s.overrides.Organisations[uuid]
+	return o, found
+}
+
+func (s *overridesStore) setStory(id string, o *storyOverride) error {
+	return s.update(func(overrides *editorialOverrides) {
+		if o == nil {
+			delete(overrides.Stories, id)
+		} else {
+			overrides.Stories[id] = *o
+		}
+	})
+}
+
+func (s *overridesStore) setOrganisation(uuid string, o *organisationOverride) error {
+	return s.update(func(overrides *editorialOverrides) {
+		if o == nil {
+			delete(overrides.Organisations, uuid)
+		} else {
+			overrides.Organisations[uuid] = *o
+		}
+	})
+}
+
+// update applies fn to a copy of the overrides and persists the result,
+// only replacing the overrides in memory once they are safely written.
+func (s *overridesStore) update(fn func(*editorialOverrides)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := editorialOverrides{
+		Stories:       make(map[string]storyOverride, len(s.overrides.Stories)),
+		Organisations: make(map[string]organisationOverride, len(s.overrides.Organisations)),
+	}
+	for k, v := range s.overrides.Stories {
+		updated.Stories[k] = v
+	}
+	for k, v := range s.overrides.Organisations {
+		updated.Organisations[k] = v
+	}
+
+	fn(&updated)
+
+	if err := writeJSONFile(s.path, updated); err != nil {
+		return err
+	}
+
+	s.overrides = updated
+	return nil
+}
+
+// applyPinsAndBlocks removes blocked stories from org and puts its pinned
+// stories first in their sections, before the sections are deduplicated and
+// paged. Pinned stories that weren't already in a section are added for
+// enrichment with the rest of the page. It returns the section each pinned
+// story is pinned to.
+func (ocs simpleOrganisationContentService) applyPinsAndBlocks(org *organisation, opts queryOptions) map[string]string {
+	orgOverride, _ := ocs.overrides.organisation(org.ID)
+
+	blocked := map[string]bool{}
+	for _, id := range orgOverride.Blocked {
+		blocked[id] = true
+	}
+
+	pinnedTo := map[string]string{}
+
+	for _, section := range sections {
+		stories := org.section(section)
+		if stories == nil || !opts.includes(section) {
+			continue
+		}
+
+		if pinned := orgOverride.Pinned[section]; len(pinned) > 0 {
+			*stories = pin(*stories, pinned)
+
+			for _, id := range pinned {
+				if _, found := pinnedTo[id]; !found {
+					pinnedTo[id] = section
+				}
+			}
+		}
+
+		*stories = ocs.removeBlocked(*stories, blocked)
+	}
+
+	return pinnedTo
+}
+
+// applyCorrections applies the story corrections to every section of org,
+// once its stories have been enriched.
+func (ocs simpleOrganisationContentService) applyCorrections(org *organisation) {
+	for _, section := range sections {
+		stories := org.section(section)
+		*stories = ocs.correctStories(*stories)
+	}
+}
+
+// removeBlocked drops stories blocked everywhere or in blocked.
+func (ocs simpleOrganisationContentService) removeBlocked(stories []content, blocked map[string]bool) []content {
+	kept := []content{}
+	for _, story := range stories {
+		if o, found := ocs.overrides.story(story.ID); blocked[story.ID] || (found && o.Blocked) {
+			continue
+		}
+		kept = append(kept, story)
+	}
+	return kept
+}
+
+// correctStories replaces the titles and images of stories that editors
+// have corrected.
+func (ocs simpleOrganisationContentService) correctStories(stories []content) []content {
+	for i, story := range stories {
+		o, found := ocs.overrides.story(story.ID)
+		if !found {
+			continue
+		}
+
+		if o.Title != "" {
+			stories[i].Title = o.Title
+		}
+		if o.ImageURL != "" {
+			stories[i].ImageURL = o.ImageURL
+			stories[i].Images = []image{{URL: o.ImageURL}}
+		}
+	}
+	return stories
+}
+
+// pin moves the pinned stories, in order, to the top of stories, adding any
+// that aren't there. The other stories keep their order after them.
+func pin(stories []content, pinned []string) []content {
+	existing := map[string]content{}
+	for _, story := range stories {
+		existing[story.ID] = story
+	}
+
+	result := []content{}
+	isPinned := map[string]bool{}
+
+	for _, id := range pinned {
+		if isPinned[id] {
+			continue
+		}
+		isPinned[id] = true
+
+		story, found := existing[id]
+		if !found {
+			story = content{ID: id}
+		}
+		result = append(result, story)
+	}
+
+	for _, story := range stories {
+		if !isPinned[story.ID] {
+			result = append(result, story)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jmcvetta/neoism"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPin(t *testing.T) {
+	stories := []content{{ID: "a", Title: "A"}, {ID: "b", Title: "B"}, {ID: "c", Title: "C"}}
+
+	got := pin(stories, []string{"c", "x", "c"})
+
+	assert.Equal(t, []content{{ID: "c", Title: "C"}, {ID: "x"}, {ID: "a", Title: "A"}, {ID: "b", Title: "B"}}, got)
+}
+
+func TestPinnedStoriesPushOthersOntoLaterPages(t *testing.T) {
+	overrides, err := newOverridesStore(t.TempDir() + "/overrides.json")
+	assert.NoError(t, err)
+
+	assert.NoError(t, overrides.setOrganisation("org", &organisationOverride{
+		Pinned:  map[string][]string{storiesSection: {"p1", "p2"}},
+		Blocked: []string{"b"},
+	}))
+	assert.NoError(t, overrides.setStory("d", &storyOverride{Blocked: true}))
+
+	ocs := simpleOrganisationContentService{overrides: overrides}
+
+	org := organisation{ID: "org", Stories: []content{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}}
+	opts := defaultQueryOptions()
+
+	pinnedTo := ocs.applyPinsAndBlocks(&org, opts)
+
+	assert.Equal(t, map[string]string{"p1": storiesSection, "p2": storiesSection}, pinnedTo)
+	assert.Equal(t, []string{"p1", "p2", "a", "c", "e"}, storyIDs(org.Stories))
+
+	seen := []string{}
+	for _, offset := range []int{0, 3} {
+		opts.pages[storiesSection] = page{limit: 3, offset: offset}
+		seen = append(seen, storyIDs(opts.paginate(&organisation{ID: "org"}, storiesSection, org.Stories))...)
+	}
+	assert.Equal(t, []string{"p1", "p2", "a", "c", "e"}, seen)
+}
+
+func TestCorrectStories(t *testing.T) {
+	overrides, err := newOverridesStore(t.TempDir() + "/overrides.json")
+	assert.NoError(t, err)
+
+	assert.NoError(t, overrides.setStory("a", &storyOverride{Title: "Fixed", ImageURL: "fixed.jpg"}))
+
+	ocs := simpleOrganisationContentService{overrides: overrides}
+
+	got := ocs.correctStories([]content{{ID: "a", Title: "Typo", ImageURL: "wrong.jpg", Images: []image{{URL: "wrong.jpg"}}}, {ID: "b", Title: "B"}})
+
+	assert.Equal(t, []content{
+		{ID: "a", Title: "Fixed", ImageURL: "fixed.jpg", Images: []image{{URL: "fixed.jpg"}}},
+		{ID: "b", Title: "B"},
+	}, got)
+}
+
+func TestIndustryContentLeavesOutBlockedStoriesInTheQuery(t *testing.T) {
+	conn := &fakeConn{}
+	ocs := newTestService(t, conn)
+	ocs.contentAPI = newTestContentAPI(t, map[string]enrichedContent{"a": {Title: "A"}, "c": {Title: "C"}})
+
+	assert.NoError(t, ocs.overrides.setStory("b", &storyOverride{Blocked: true}))
+
+	conn.fn = func(queries []*neoism.CypherQuery) error {
+		assert.Equal(t, []string{"b"}, queries[0].Parameters["blocked"])
+
+		// The page of two, with one more to show there is another.
+		*queries[0].Result.(*[]industryContent) = []industryContent{{ID: "ind", Stories: []content{{ID: "a"}, {ID: "c"}, {ID: "d"}}}}
+		return nil
+	}
+
+	opts := defaultQueryOptions()
+	opts.pages[storiesSection] = page{limit: 2}
+
+	ic, found, err := ocs.getIndustryContent("ind", opts)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"a", "c"}, storyIDs(ic.Stories))
+	assert.Equal(t, "C", ic.Stories[1].Title)
+	assert.Empty(t, ic.Warnings)
+	assert.Equal(t, "/industries/ind/content?stories.limit=2&stories.offset=2", ic.Next)
+}
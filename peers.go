@@ -0,0 +1,65 @@
+package main
+
+import (
+	"time"
+
+	"github.com/jmcvetta/neoism"
+)
+
+const defaultPeersLimit = 20
+
+// industryPeers are the organisations sharing an organisation's industry
+// classification, most written about in the time window first.
+type industryPeers struct {
+	ID                     string `json:"id"`
+	IndustryClassification string `json:"industryClassification,omitempty"`
+	Peers                  []peer `json:"peers"`
+}
+
+type peer struct {
+	ID        string `json:"id"`
+	PrefLabel string `json:"prefLabel"`
+	Mentions  int    `json:"mentions"`
+}
+
+// peersQuery counts the content in window mentioning each organisation in
+// the same industry classification as uuid. Organisations sharing several
+// classifications with it are only counted once.
+func peersQuery(uuid string, window timeWindow, limit int, result *[]industryPeers) *neoism.CypherQuery {
+	props := window.props(uuid)
+	props["limit"] = limit
+
+	return &neoism.CypherQuery{
+		Statement: `
+		MATCH (o:Organisation {uuid:{uuid}})
+		OPTIONAL MATCH (o)--(i:IndustryClassification)
+		WITH o, head(collect(i.prefLabel)) as industry
+		OPTIONAL MATCH (o)--(:IndustryClassification)--(comp:Organisation)
+		WHERE comp <> o
+		WITH DISTINCT o, industry, comp
+		OPTIONAL MATCH (comp)-[:MENTIONS]-(c:Content)
+		WHERE c.publishedDateEpoch > {secondsSinceEpoch} AND c.publishedDateEpoch <= {untilSecondsSinceEpoch}
+		WITH o, industry, comp, count(DISTINCT c) as mentions
+		ORDER BY mentions DESC, comp.prefLabel ASC
+		WITH o, industry, [p IN collect({ID:comp.uuid, PrefLabel:comp.prefLabel, Mentions:mentions}) WHERE p.ID IS NOT NULL] as peers
+		RETURN o.uuid as ID, industry as IndustryClassification, peers[..{limit}] as Peers`,
+		Parameters: props,
+		Result:     result,
+	}
+}
+
+// getPeers ranks the organisation's industry peers by how often they were
+// mentioned in the options' time window.
+func (ocs simpleOrganisationContentService) getPeers(uuid string, opts queryOptions, limit int) (industryPeers, bool, error) {
+	results := []industryPeers{}
+
+	if err := ocs.conn.CypherBatch([]*neoism.CypherQuery{peersQuery(uuid, opts.bounds(time.Now()), limit, &results)}); err != nil {
+		return industryPeers{}, false, err
+	}
+
+	if len(results) == 0 {
+		return industryPeers{}, false, nil
+	}
+
+	return results[0], true, nil
+}
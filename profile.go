@@ -0,0 +1,90 @@
+package main
+
+import "github.com/jmcvetta/neoism"
+
+// profileResult is a row of profileQuery. Singular relations are collected
+// so that an organisation with, say, two parents doesn't multiply the rows.
+type profileResult struct {
+	Aliases                []string
+	LEIs                   []string
+	IndustryClassification concept
+	Parents                []concept
+	Subsidiaries           []concept
+	FinancialInstruments   []financialInstrument
+	Memberships            []membership
+}
+
+// profileQuery is the "complex query from public organisations api" in
+// queries.txt, matching the organisation by UUID rather than UPP identifier.
+func profileQuery(uuid string, result *[]profileResult) *neoism.CypherQuery {
+	return &neoism.CypherQuery{
+		Statement: `
+		MATCH (o:Organisation {uuid:{uuid}})
+		OPTIONAL MATCH (o)<-[:HAS_ORGANISATION]-(m:Membership)-[:HAS_MEMBER]->(p:Person)
+		WITH o, m, p, size((p)<-[:MENTIONS]-(:Content)-[:MENTIONS]->(o)) as annCount
+		WITH o, {ID:m.uuid, PrefLabel:m.prefLabel, InceptionDate:m.inceptionDate, TerminationDate:m.terminationDate, AnnCount:annCount, Person:{ID:p.uuid, PrefLabel:p.prefLabel}} as m
+		ORDER BY m.AnnCount DESC, m.Person.PrefLabel ASC LIMIT 1000
+		WITH o, collect(m) as memberships
+		OPTIONAL MATCH (o)-[:HAS_CLASSIFICATION]->(ind:IndustryClassification)
+		WITH o, memberships, {ID:ind.uuid, PrefLabel:ind.prefLabel} as ind
+		OPTIONAL MATCH (lei:LegalEntityIdentifier)-[:IDENTIFIES]->(o)
+		WITH o, memberships, ind, collect(lei.value) as leis
+		OPTIONAL MATCH (o)-[:SUB_ORGANISATION_OF]->(parent:Organisation)
+		WITH o, memberships, ind, leis, collect({ID:parent.uuid, PrefLabel:parent.prefLabel}) as parents
+		OPTIONAL MATCH (o)<-[:SUB_ORGANISATION_OF]-(sub:Organisation)
+		WITH o, memberships, ind, leis, parents, {ID:sub.uuid, PrefLabel:sub.prefLabel, AnnCount:size((:Content)-[:MENTIONS]->(sub))} as sub
+		ORDER BY sub.AnnCount DESC, sub.PrefLabel ASC
+		WITH o, memberships, ind, leis, parents, collect(sub) as subs
+		OPTIONAL MATCH (o)<-[:ISSUED_BY]-(fi:FinancialInstrument)<-[:IDENTIFIES]-(figi:FIGIIdentifier)
+		WITH o, memberships, ind, leis, parents, subs, collect({ID:fi.uuid, PrefLabel:fi.prefLabel, FIGI:figi.value}) as fis
+		RETURN o.aliases as Aliases, leis as LEIs, ind as IndustryClassification, parents as Parents,
+			subs as Subsidiaries, fis as FinancialInstruments, memberships as Memberships
+		LIMIT 1`,
+		Parameters: neoism.Props{"uuid": uuid},
+		Result:     result,
+	}
+}
+
+// toProfile drops the empty placeholders left by unmatched optional matches.
+func (r profileResult) toProfile() *profile {
+	p := &profile{Aliases: r.Aliases}
+
+	if len(r.LEIs) > 0 {
+		p.LegalEntityIdentifier = r.LEIs[0]
+	}
+
+	if r.IndustryClassification.ID != "" {
+		ind := r.IndustryClassification
+		p.IndustryClassification = &ind
+	}
+
+	for _, parent := range r.Parents {
+		if parent.ID != "" {
+			parent := parent
+			p.Parent = &parent
+			break
+		}
+	}
+
+	for _, sub := range r.Subsidiaries {
+		if sub.ID != "" {
+			p.Subsidiaries = append(p.Subsidiaries, sub)
+		}
+	}
+
+	for _, fi := range r.FinancialInstruments {
+		if fi.ID != "" {
+			fi := fi
+			p.FinancialInstrument = &fi
+			break
+		}
+	}
+
+	for _, m := range r.Memberships {
+		if m.ID != "" {
+			p.Memberships = append(p.Memberships, m)
+		}
+	}
+
+	return p
+}
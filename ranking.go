@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sortRecent    = "recent"
+	sortRelevance = "relevance"
+)
+
+// relevanceHalfLife is the age at which a story's recency score has halved.
+const relevanceHalfLife = 72 * time.Hour
+
+// maxRelevanceCandidates is how many of the organisation's most recent
+// stories are enriched and ranked when sorting by relevance. Every page is
+// cut from the same candidates, so pages never overlap.
+const maxRelevanceCandidates = 50
+
+// predicateWeights score how strongly an annotation ties a story to the
+// organisation, by relationship type in the graph.
+var predicateWeights = map[string]float64{
+	"ABOUT":                      1.0,
+	"IS_PRIMARILY_CLASSIFIED_BY": 0.9,
+	"MAJOR_MENTIONS":             0.7,
+	"IS_CLASSIFIED_BY":           0.5,
+	"MENTIONS":                   0.3,
+}
+
+// Bonuses for stories editors have marked as standing out.
+const (
+	editorsChoiceWeight = 0.3
+	exclusiveWeight     = 0.2
+	scoopWeight         = 0.3
+)
+
+// rankedPredicates are the relationships matched for the organisation's own
+// stories under each sort order, as a Cypher relationship type pattern such
+// as MENTIONS|ABOUT.
+func rankedPredicates(order string) string {
+	if order != sortRelevance {
+		return "MENTIONS"
+	}
+
+	predicates := []string{}
+	for predicate := range predicateWeights {
+		predicates = append(predicates, predicate)
+	}
+	sort.Strings(predicates)
+
+	return strings.Join(predicates, "|")
+}
+
+// relevance scores an enriched story on how recently it was published, how
+// strongly it is annotated with the organisation and its standout flags.
+func relevance(story content, now time.Time) float64 {
+	score := 0.0
+
+	if published, err := time.Parse(time.RFC3339, story.PublishedDate); err == nil {
+		age := now.Sub(published)
+		if age < 0 {
+			age = 0
+		}
+		score += math.Exp2(-float64(age) / float64(relevanceHalfLife))
+	}
+
+	strongest := 0.0
+	for _, predicate := range story.Predicates {
+		strongest = math.Max(strongest, predicateWeights[predicate])
+	}
+	score += strongest
+
+	if story.Standout != nil {
+		if story.Standout.EditorsChoice {
+			score += editorsChoiceWeight
+		}
+		if story.Standout.Exclusive {
+			score += exclusiveWeight
+		}
+		if story.Standout.Scoop {
+			score += scoopWeight
+		}
+	}
+
+	return score
+}
+
+// rankByRelevance scores the stories and orders them most relevant first.
+func rankByRelevance(stories []content, now time.Time) {
+	for i := range stories {
+		stories[i].Relevance = relevance(stories[i], now)
+	}
+
+	sort.SliceStable(stories, func(i, j int) bool { return stories[i].Relevance > stories[j].Relevance })
+}
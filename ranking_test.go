@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankedPredicates(t *testing.T) {
+	assert.Equal(t, "MENTIONS", rankedPredicates(sortRecent))
+	assert.Equal(t, "MENTIONS", rankedPredicates(""))
+	assert.Equal(t, "ABOUT|IS_CLASSIFIED_BY|IS_PRIMARILY_CLASSIFIED_BY|MAJOR_MENTIONS|MENTIONS", rankedPredicates(sortRelevance))
+}
+
+func TestRelevance(t *testing.T) {
+	now := time.Date(2017, 6, 10, 12, 0, 0, 0, time.UTC)
+	published := func(age time.Duration) string { return now.Add(-age).Format(time.RFC3339) }
+
+	tests := []struct {
+		name  string
+		story content
+		want  float64
+	}{
+		{"no date or predicates", content{}, 0},
+		{"unparseable date", content{PublishedDate: "yesterday"}, 0},
+		{"just published", content{PublishedDate: published(0)}, 1},
+		{"one half-life old", content{PublishedDate: published(relevanceHalfLife)}, 0.5},
+		{"two half-lives old", content{PublishedDate: published(2 * relevanceHalfLife)}, 0.25},
+		{"published in the future", content{PublishedDate: published(-time.Hour)}, 1},
+		{"unknown predicate", content{Predicates: []string{"HAS_AUTHOR"}}, 0},
+		{"strongest predicate counts", content{Predicates: []string{"MENTIONS", "ABOUT", "MAJOR_MENTIONS"}}, 1},
+		{"editor's choice", content{Standout: &standout{EditorsChoice: true}}, editorsChoiceWeight},
+		{"exclusive scoop", content{Standout: &standout{Exclusive: true, Scoop: true}}, exclusiveWeight + scoopWeight},
+		{
+			"everything",
+			content{PublishedDate: published(relevanceHalfLife), Predicates: []string{"MENTIONS"}, Standout: &standout{EditorsChoice: true, Exclusive: true, Scoop: true}},
+			0.5 + 0.3 + editorsChoiceWeight + exclusiveWeight + scoopWeight,
+		},
+	}
+
+	for _, test := range tests {
+		assert.InDelta(t, test.want, relevance(test.story, now), 1e-9, test.name)
+	}
+}
+
+func TestRankByRelevance(t *testing.T) {
+	now := time.Date(2017, 6, 10, 12, 0, 0, 0, time.UTC)
+	day := func(n int) string { return now.AddDate(0, 0, -n).Format(time.RFC3339) }
+
+	stories := []content{
+		{ID: "recent-mention", PublishedDate: day(0), Predicates: []string{"MENTIONS"}},
+		{ID: "older-about", PublishedDate: day(3), Predicates: []string{"ABOUT"}},
+		{ID: "old-mention", PublishedDate: day(30), Predicates: []string{"MENTIONS"}},
+		{ID: "old-mention-twin", PublishedDate: day(30), Predicates: []string{"MENTIONS"}},
+		{ID: "scoop", PublishedDate: day(0), Predicates: []string{"MENTIONS"}, Standout: &standout{Scoop: true}},
+	}
+
+	rankByRelevance(stories, now)
+
+	assert.Equal(t, []string{"scoop", "older-about", "recent-mention", "old-mention", "old-mention-twin"}, storyIDs(stories))
+	for i := 1; i < len(stories); i++ {
+		assert.True(t, stories[i-1].Relevance >= stories[i].Relevance)
+	}
+	assert.InDelta(t, 1.3, stories[2].Relevance, 1e-9)
+}
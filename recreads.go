@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// errRecommendedReadsUnavailable is returned without calling the service
+// while the circuit breaker is open.
+var errRecommendedReadsUnavailable = errors.New("recommended reads unavailable: circuit open")
+
+// recommendedReadsError describes a failed call to the recommended reads
+// service. StatusCode is zero if no response was received.
+type recommendedReadsError struct {
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+func (e *recommendedReadsError) Error() string {
+	switch {
+	case e.Err == nil:
+		return fmt.Sprintf("recommended reads request to %s returned status %d", e.URL, e.StatusCode)
+	case e.StatusCode == 0:
+		return fmt.Sprintf("recommended reads request to %s failed: %s", e.URL, e.Err)
+	default:
+		return fmt.Sprintf("recommended reads request to %s returned an invalid body: %s", e.URL, e.Err)
+	}
+}
+
+// retryable reports whether the call might succeed if made again.
+func (e *recommendedReadsError) retryable() bool {
+	return e.StatusCode == 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+type recommendedReadsDoc struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+type recommendedReadsRequest struct {
+	Doc recommendedReadsDoc `json:"doc"`
+}
+
+// recommendedReadsClient calls the contextual recommendations endpoint of
+// the recommended reads API. Each attempt is bounded by the client timeout;
+// failed attempts are retried with exponential backoff, and repeated
+// failures open the circuit breaker.
+type recommendedReadsClient struct {
+	baseURL    string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+	breaker    *circuitBreaker
+}
+
+func newRecommendedReadsClient(baseURL string, timeout time.Duration, retries int, backoff time.Duration, breaker *circuitBreaker) *recommendedReadsClient {
+	return &recommendedReadsClient{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Transport: httpClient.Transport, Timeout: timeout},
+		retries:    retries,
+		backoff:    backoff,
+		breaker:    breaker,
+	}
+}
+
+// recommend returns up to count articles related to doc, with the
+// recommender's explanation of each if explain is set.
+func (c *recommendedReadsClient) recommend(doc recommendedReadsDoc, count int, explain bool) ([]article, error) {
+	if !c.breaker.allow() {
+		return nil, errRecommendedReadsUnavailable
+	}
+
+	body, err := json.Marshal(recommendedReadsRequest{doc})
+	if err != nil {
+		return nil, err
+	}
+
+	reqURL := fmt.Sprintf("%s/recommended-reads-api/recommend/contextual/doc?count=%d&sort=rel&explain=%t", c.baseURL, count, explain)
+
+	var articles []article
+
+	for attempt := 0; ; attempt++ {
+		articles, err = c.post(reqURL, body)
+
+		rrErr, isRRErr := err.(*recommendedReadsError)
+		if err == nil || !isRRErr || !rrErr.retryable() || attempt == c.retries {
+			break
+		}
+
+		wait := c.backoff << uint(attempt)
+		log.Printf("Retrying reqURL=%s in %s, err=%s", reqURL, wait, err)
+		time.Sleep(wait)
+	}
+
+	if rrErr, isRRErr := err.(*recommendedReadsError); isRRErr && rrErr.retryable() {
+		c.breaker.failure()
+	} else {
+		c.breaker.success()
+	}
+
+	return articles, err
+}
+
+func (c *recommendedReadsClient) post(reqURL string, body []byte) ([]article, error) {
+	request, err := http.NewRequest("POST", reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, &recommendedReadsError{URL: reqURL, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return []article{}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &recommendedReadsError{URL: reqURL, StatusCode: resp.StatusCode}
+	}
+
+	target := recommendedReads{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&target); err != nil {
+		return nil, &recommendedReadsError{URL: reqURL, StatusCode: resp.StatusCode, Err: err}
+	}
+
+	return target.Articles, nil
+}
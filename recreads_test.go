@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recReadsStub is an httptest stand-in for the recommended reads API that
+// answers each request with the next of its handlers, repeating the last.
+type recReadsStub struct {
+	mu       sync.Mutex
+	handlers []http.HandlerFunc
+	requests []time.Time
+}
+
+func (s *recReadsStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	n := len(s.requests)
+	s.requests = append(s.requests, time.Now())
+	handler := s.handlers[len(s.handlers)-1]
+	if n < len(s.handlers) {
+		handler = s.handlers[n]
+	}
+	s.mu.Unlock()
+
+	handler(w, r)
+}
+
+func (s *recReadsStub) calls() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time{}, s.requests...)
+}
+
+func status(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}
+}
+
+func articles(titles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reads := recommendedReads{}
+		for _, title := range titles {
+			reads.Articles = append(reads.Articles, article{ID: "id-" + title, Title: title})
+		}
+		json.NewEncoder(w).Encode(reads)
+	}
+}
+
+func newTestRecReads(t *testing.T, timeout time.Duration, retries int, backoff time.Duration, handlers ...http.HandlerFunc) (*recommendedReadsClient, *recReadsStub) {
+	stub := &recReadsStub{handlers: handlers}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	return newRecommendedReadsClient(server.URL, timeout, retries, backoff, newCircuitBreaker(0, 0)), stub
+}
+
+func TestRecommendedReadsRetriesWithBackoff(t *testing.T) {
+	for _, code := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		backoff := 20 * time.Millisecond
+		c, stub := newTestRecReads(t, time.Second, 2, backoff, status(code), status(code), articles("a"))
+
+		got, err := c.recommend(recommendedReadsDoc{Title: "Acme"}, 5, false)
+
+		assert.NoError(t, err, "status %d", code)
+		assert.Len(t, got, 1)
+
+		calls := stub.calls()
+		if assert.Len(t, calls, 3, "status %d", code) {
+			assert.True(t, calls[1].Sub(calls[0]) >= backoff, "first retry waits for the backoff")
+			assert.True(t, calls[2].Sub(calls[1]) >= 2*backoff, "second retry waits twice as long")
+		}
+	}
+}
+
+func TestRecommendedReadsGivesUpAfterRetries(t *testing.T) {
+	c, stub := newTestRecReads(t, time.Second, 2, time.Millisecond, status(http.StatusBadGateway))
+
+	_, err := c.recommend(recommendedReadsDoc{}, 5, false)
+
+	rrErr, ok := err.(*recommendedReadsError)
+	if assert.True(t, ok, "typed error") {
+		assert.Equal(t, http.StatusBadGateway, rrErr.StatusCode)
+	}
+	assert.Len(t, stub.calls(), 3)
+}
+
+func TestRecommendedReadsDoesNotRetryClientErrors(t *testing.T) {
+	for _, code := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnprocessableEntity} {
+		c, stub := newTestRecReads(t, time.Second, 2, time.Millisecond, status(code))
+
+		_, err := c.recommend(recommendedReadsDoc{}, 5, false)
+
+		rrErr, ok := err.(*recommendedReadsError)
+		if assert.True(t, ok, "status %d", code) {
+			assert.Equal(t, code, rrErr.StatusCode)
+		}
+		assert.Len(t, stub.calls(), 1, "status %d", code)
+	}
+}
+
+func TestRecommendedReadsNotFoundIsEmpty(t *testing.T) {
+	c, stub := newTestRecReads(t, time.Second, 2, time.Millisecond, status(http.StatusNotFound))
+
+	got, err := c.recommend(recommendedReadsDoc{}, 5, false)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, got)
+	assert.Empty(t, got)
+	assert.Len(t, stub.calls(), 1)
+}
+
+func TestRecommendedReadsTimesOutEachAttempt(t *testing.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	c, stub := newTestRecReads(t, 50*time.Millisecond, 1, time.Millisecond, slow)
+
+	start := time.Now()
+	_, err := c.recommend(recommendedReadsDoc{}, 5, false)
+
+	rrErr, ok := err.(*recommendedReadsError)
+	if assert.True(t, ok, "typed error") {
+		assert.Equal(t, 0, rrErr.StatusCode)
+		assert.True(t, rrErr.retryable())
+	}
+	assert.Len(t, stub.calls(), 2)
+	assert.True(t, time.Since(start) < time.Second, "attempts were cut short")
+}
+
+func TestRecommendedReadsEncodesDocAsJSON(t *testing.T) {
+	doc := recommendedReadsDoc{Title: `The "Big" Merger`, Content: "Shares rose 5%\n\n\"We're delighted\", said the CEO \\ chairman"}
+
+	var received recommendedReadsRequest
+	var decodeErr error
+
+	c, _ := newTestRecReads(t, time.Second, 0, time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		decodeErr = json.NewDecoder(r.Body).Decode(&received)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "7", r.URL.Query().Get("count"))
+		assert.Equal(t, "true", r.URL.Query().Get("explain"))
+		articles()(w, r)
+	})
+
+	_, err := c.recommend(doc, 7, true)
+
+	assert.NoError(t, err)
+	assert.NoError(t, decodeErr)
+	assert.Equal(t, doc, received.Doc)
+}
+
+func TestRecommendedReadsInvalidBodyIsTypedError(t *testing.T) {
+	c, stub := newTestRecReads(t, time.Second, 2, time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"articles": [`))
+	})
+
+	_, err := c.recommend(recommendedReadsDoc{}, 5, false)
+
+	rrErr, ok := err.(*recommendedReadsError)
+	if assert.True(t, ok, "typed error") {
+		assert.Equal(t, http.StatusOK, rrErr.StatusCode)
+		assert.Error(t, rrErr.Err)
+		assert.False(t, rrErr.retryable())
+	}
+	assert.Len(t, stub.calls(), 1)
+}
+
+func TestRecommendedReadsOpensBreaker(t *testing.T) {
+	c, stub := newTestRecReads(t, time.Second, 0, time.Millisecond, status(http.StatusInternalServerError))
+	c.breaker = newCircuitBreaker(2, time.Minute)
+
+	c.recommend(recommendedReadsDoc{}, 5, false)
+	c.recommend(recommendedReadsDoc{}, 5, false)
+
+	_, err := c.recommend(recommendedReadsDoc{}, 5, false)
+
+	assert.Equal(t, errRecommendedReadsUnavailable, err)
+	assert.Len(t, stub.calls(), 2, "no call while the circuit is open")
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := newCircuitBreaker(3, time.Minute)
+	b.now = clock.now
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, b.allow())
+		b.failure()
+	}
+	assert.True(t, b.allow(), "closed below the threshold")
+
+	b.failure()
+	assert.False(t, b.allow(), "open at the threshold")
+
+	clock.t = clock.t.Add(59 * time.Second)
+	assert.False(t, b.allow(), "open during the cooldown")
+
+	clock.t = clock.t.Add(time.Second)
+	assert.True(t, b.allow(), "one trial after the cooldown")
+	assert.False(t, b.allow(), "only one trial at a time")
+
+	b.failure()
+	assert.False(t, b.allow(), "reopened by a failed trial")
+
+	clock.t = clock.t.Add(time.Minute)
+	assert.True(t, b.allow(), "another trial after the next cooldown")
+
+	b.success()
+	assert.True(t, b.allow(), "closed by a successful trial")
+	assert.True(t, b.allow())
+
+	b.failure()
+	assert.True(t, b.allow(), "failures are counted afresh once closed")
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(0, time.Minute)
+
+	for i := 0; i < 10; i++ {
+		b.failure()
+	}
+	assert.True(t, b.allow())
+}
+
+func TestRecommendedReadsAskForTheRequestedPage(t *testing.T) {
+	counts := make(chan string, 1)
+	c, _ := newTestRecReads(t, time.Second, 0, 0, func(w http.ResponseWriter, r *http.Request) {
+		counts <- r.URL.Query().Get("count")
+		articles("a")(w, r)
+	})
+
+	ocs := simpleOrganisationContentService{recReads: c}
+
+	_, err := ocs.getContentFromRecommendedReads(recommendedReadsDoc{}, page{limit: 5, offset: 10}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "21", <-counts)
+}
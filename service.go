@@ -1,10 +1,8 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"strings"
 	"time"
 
@@ -12,263 +10,512 @@ import (
 	"github.com/jmcvetta/neoism"
 )
 
-var descMap = map[string]string{
-	"a14bcf4b-556d-31a6-8bbc-3d53d0366999": "Agropur cooperative processes and distributes dairy products. Its products include industrial cheese, yogurt, and products associated with fluid milk.",
-	"296db2c2-2c98-3e47-8e2a-e85bdfc1beae": "Ferrovial, S.A. , previously Grupo Ferrovial, is a Spanish multinational company involved in the design, construction, financing, operation (DBFO) and maintenance of transport, urban and services infrastructure.",
-	"013f7fa7-aa26-3e20-84f1-fb8e5f7383ff": "Barclays is a British multinational banking and financial services company headquartered in London.",
+type organisationContentService interface {
+	getContentByOrganisationUUID(uuid string, opts queryOptions) (organisation, bool, error)
+	resolveIdentifier(authority string, value string) (string, bool, error)
+	getContentByOrganisationUUIDs(uuids []string, opts queryOptions) (map[string]batchEntry, error)
+	getPeers(uuid string, opts queryOptions, limit int) (industryPeers, bool, error)
+	getSubsidiaryTree(uuid string, depth int, stories int, opts queryOptions) (*subsidiaryNode, bool, error)
+	getIndustry(uuid string) (industry, bool, error)
+	getIndustryContent(uuid string, opts queryOptions) (industryContent, bool, error)
 }
 
-var resultsMap = map[string]organisation{}
+type simpleOrganisationContentService struct {
+	conn             neoutils.NeoConnection
+	recReads         *recommendedReadsClient
+	contentAPI       *enrichedContentClient
+	enrichPool       *workerPool
+	cache            *organisationCache
+	inflight         *callGroup
+	descriptions     descriptionProvider
+	overrides        *overridesStore
+	tagRules         tagRules
+	sectionPriority  []string
+	batchConcurrency int
+}
 
-type organisationContentService interface {
-	getContentByOrganisationUUID(uuid string) (organisation, bool, error)
+func newOrganisationContentService(conn neoutils.NeoConnection, recReads *recommendedReadsClient, contentAPI *enrichedContentClient, enrichPool *workerPool, cache *organisationCache, descriptions descriptionProvider, overrides *overridesStore, tagRules tagRules, sectionPriority []string, batchConcurrency int) simpleOrganisationContentService {
+	return simpleOrganisationContentService{conn, recReads, contentAPI, enrichPool, cache, newCallGroup(), descriptions, overrides, tagRules, sectionPriority, batchConcurrency}
 }
 
-type simpleOrganisationContentService struct {
-	conn        neoutils.NeoConnection
-	recReadsURL string
-	apiKey      string
+func (ocs simpleOrganisationContentService) getContentByOrganisationUUID(uuid string, opts queryOptions) (organisation, bool, error) {
+	key := opts.cacheKey(uuid)
+
+	if org, found := ocs.cache.get(key); found {
+		return org, true, nil
+	}
+
+	// Concurrent misses for the same organisation share a single load.
+	return ocs.inflight.do(key, func() (organisation, bool, error) {
+		return ocs.loadOrganisation(uuid, opts)
+	})
+}
+
+func (ocs simpleOrganisationContentService) loadOrganisation(uuid string, opts queryOptions) (organisation, bool, error) {
+	key := opts.cacheKey(uuid)
+
+	// Another caller may have filled the cache between our miss and
+	// becoming the leader for this key.
+	if org, found := ocs.cache.get(key); found {
+		return org, true, nil
+	}
+
+	load := newOrganisationLoad(uuid, opts, time.Now(), ocs.cache.generation(uuid))
+
+	if err := ocs.conn.CypherBatch(load.queries); err != nil {
+		return organisation{}, false, err
+	}
+
+	return ocs.assembleOrganisation(load)
 }
 
-func newOrganisationContentService(conn neoutils.NeoConnection, recReadsURL string, apiKey string) simpleOrganisationContentService {
-	return simpleOrganisationContentService{conn, recReadsURL, apiKey}
+// organisationLoad holds the Neo4j queries for an organisation, which are
+// run together in one batch, and their results. generation is the
+// organisation's cache generation from before the queries were run.
+type organisationLoad struct {
+	uuid           string
+	opts           queryOptions
+	now            time.Time
+	generation     uint64
+	queries        []*neoism.CypherQuery
+	results        []organisation
+	stories        []content
+	parents        []content
+	subsidiaries   []content
+	siblings       []content
+	people         []content
+	industry       []content
+	profileResults []profileResult
 }
 
-func (ocs simpleOrganisationContentService) getContentByOrganisationUUID(uuid string) (organisation, bool, error) {
+func newOrganisationLoad(uuid string, opts queryOptions, now time.Time, generation uint64) *organisationLoad {
+	load := &organisationLoad{
+		uuid:         uuid,
+		opts:         opts,
+		now:          now,
+		generation:   generation,
+		parents:      []content{},
+		subsidiaries: []content{},
+		siblings:     []content{},
+		people:       []content{},
+		industry:     []content{},
+	}
 
-	org, found := resultsMap[uuid]
+	window := opts.bounds(now)
 
-	if !found {
-		results := []organisation{}
+	query := &neoism.CypherQuery{
+		Statement: `
+		MATCH (o:Organisation {uuid:{uuid}})
+		OPTIONAL MATCH (o)--(i:IndustryClassification)
+		RETURN o.prefLabel as Title, i.prefLabel as IndustryClassification, o.uuid as ID, o.description as Description`,
+		Parameters: neoism.Props{"uuid": uuid},
+		Result:     &load.results,
+	}
 
-		now := time.Now()
+	// Stories sorted by relevance are ranked from the same most recent
+	// stories whichever page is requested, so that pages don't overlap.
+	storiesQuery := &neoism.CypherQuery{
+		Statement: fmt.Sprintf(`
+		MATCH (o:Organisation {uuid:{uuid}})-[r:%s]-(c:Content)
+		WHERE c.publishedDateEpoch > {secondsSinceEpoch} AND c.publishedDateEpoch <= {untilSecondsSinceEpoch}
+		WITH c, collect(type(r)) as Predicates
+		RETURN c.title as Title, c.uuid as ID, c.publishedDate as PublishedDate, Predicates
+		ORDER BY c.publishedDateEpoch DESC
+		LIMIT {limit}`, rankedPredicates(opts.sort)),
+		Parameters: window.sectionProps(uuid),
+		Result:     &load.stories,
+	}
 
-		threeMonthsAgo := now.AddDate(0, -3, 0)
+	if opts.sort == sortRelevance {
+		// Only the candidates are ranked and paged through, so no more are
+		// needed to tell whether there is a page after the last.
+		storiesQuery.Parameters["limit"] = maxRelevanceCandidates
+	}
 
-		secondsSinceEpoch := threeMonthsAgo.Unix()
+	load.queries = []*neoism.CypherQuery{query, storiesQuery, profileQuery(uuid, &load.profileResults)}
 
-		query := &neoism.CypherQuery{
-			Statement: `
-			MATCH (o:Organisation {uuid:{uuid}})
-			OPTIONAL MATCH (o)--(i:IndustryClassification)
-	    OPTIONAL MATCH (o)-[:MENTIONS]-(c:Content)
-	    WHERE c.publishedDateEpoch > {secondsSinceEpoch}
-	    WITH o, i, {Title:c.title, ID:c.uuid, PublishedDate:c.publishedDate} as stories
-	    WITH o, i, collect(stories) as stories
-	    RETURN o.prefLabel as Title, i.prefLabel as IndustryClassification, stories as Stories, o.uuid as ID`,
-			Parameters: neoism.Props{"uuid": uuid, "secondsSinceEpoch": secondsSinceEpoch},
-			Result:     &results,
-		}
+	if opts.includes(parentsSection) {
+		load.queries = append(load.queries, relatedOrganisationQuery(parentPattern, uuid, window, &load.parents))
+	}
 
-		if err := ocs.conn.CypherBatch([]*neoism.CypherQuery{query}); err != nil {
-			return organisation{}, false, err
-		} else if len(results) == 0 {
-			errMsg := fmt.Sprintf("No organisation found for uuid:%s", uuid)
-			log.Print(errMsg)
-			return organisation{}, false, nil
-		}
+	if opts.includes(subsidiariesSection) {
+		load.queries = append(load.queries, relatedOrganisationQuery(subsidiaryPattern, uuid, window, &load.subsidiaries))
+	}
 
-		org = organisation{
-			Title: results[0].Title,
-			IndustryClassification: results[0].IndustryClassification,
-			ID: results[0].ID,
-		}
+	if opts.includes(siblingsSection) {
+		load.queries = append(load.queries, relatedOrganisationQuery(siblingPattern, uuid, window, &load.siblings))
+	}
 
-		if len(results[0].Stories) > 0 && results[0].Stories[0].ID != "" {
-			org.Stories = ocs.enrichContentList(results[0].Stories[0:5])
-		}
+	if opts.includes(peopleSection) {
+		load.queries = append(load.queries, peopleQuery(uuid, window, &load.people))
+	}
 
-		subsidContent := []content{}
-
-		subsidQuery := &neoism.CypherQuery{
-			Statement: `
-			MATCH (n:Organisation {uuid:{uuid}})-[:SUB_ORGANISATION_OF]-(s:Organisation)-[:MENTIONS]-(c:Content)
-			WHERE c.publishedDateEpoch > {secondsSinceEpoch}
-			WITH c, {Label:s.prefLabel} as Tags
-			WITH c, collect(Tags) as Tags
-			RETURN c.title as Title, c.uuid as ID, Tags as Tags, c.publishedDate as PublishedDate
-			LIMIT(5)`,
-			Parameters: neoism.Props{"uuid": uuid, "secondsSinceEpoch": secondsSinceEpoch},
-			Result:     &subsidContent,
+	if opts.includes(industrySection) {
+		load.queries = append(load.queries, industryStoriesQuery(uuid, window, &load.industry))
+	}
+
+	return load
+}
+
+// assembleOrganisation builds the organisation from the results of load,
+// adding its recommended reads and enriching every story, and caches it.
+func (ocs simpleOrganisationContentService) assembleOrganisation(load *organisationLoad) (organisation, bool, error) {
+	uuid, opts, now, results := load.uuid, load.opts, load.now, load.results
+	key := opts.cacheKey(uuid)
+
+	if len(results) == 0 {
+		errMsg := fmt.Sprintf("No organisation found for uuid:%s", uuid)
+		log.Print(errMsg)
+		return organisation{}, false, nil
+	}
+
+	org := organisation{
+		Title:                  results[0].Title,
+		IndustryClassification: results[0].IndustryClassification,
+		ID:                     results[0].ID,
+	}
+
+	if len(load.profileResults) > 0 {
+		org.Profile = load.profileResults[0].toProfile()
+	}
+
+	// The first page of the organisation's own stories is enriched straight
+	// away, as their standfirsts help pick recommended reads. Every other
+	// story is enriched once the sections have been deduplicated and paged,
+	// which is also when warnings are recorded for the stories still shown.
+	enriched := map[string]error{}
+
+	if len(load.stories) > 0 && opts.includes(storiesSection) {
+		toEnrich := opts.pageOf(storiesSection, load.stories)
+		if opts.sort == sortRelevance {
+			toEnrich = load.stories
 		}
 
-		if err := ocs.conn.CypherBatch([]*neoism.CypherQuery{subsidQuery}); err != nil {
-			return organisation{}, false, err
+		for i, err := range ocs.enrichStories(toEnrich, opts.image) {
+			enriched[toEnrich[i].ID] = err
 		}
 
-		log.Printf("Subsids: %v", subsidContent)
+		org.Stories = load.stories
 
-		if len(subsidContent) > 0 {
-			org.SubsidStories = ocs.enrichContentList(subsidContent)
+		if opts.sort == sortRelevance {
+			rankByRelevance(org.Stories, now)
 		}
+	}
 
-		if org.IndustryClassification != "" {
-			indClassContent := []content{}
-
-			indClassQuery := &neoism.CypherQuery{
-				Statement: `MATCH (n:Organisation {uuid:{uuid}})--(i:IndustryClassification)--(comp:Organisation)-[m:MENTIONS]-(c:Content)
-				WHERE c.publishedDateEpoch > {secondsSinceEpoch}
-				WITH c, {Label:comp.prefLabel} as Tags
-				WITH c, collect(Tags) as Tags
-				RETURN DISTINCT c.title as Title, c.uuid as ID, Tags as Tags, c.publishedDate as PublishedDate
-				ORDER BY PublishedDate DESC
-				LIMIT(5)`,
-				Parameters: neoism.Props{"uuid": uuid, "secondsSinceEpoch": secondsSinceEpoch},
-				Result:     &indClassContent,
-			}
+	org.ParentStories = load.parents
+	org.SubsidStories = load.subsidiaries
+	org.SiblingStories = load.siblings
+	org.PeopleStories = load.people
 
-			if err := ocs.conn.CypherBatch([]*neoism.CypherQuery{indClassQuery}); err != nil {
-				return organisation{}, false, err
-			}
+	if org.IndustryClassification != "" && opts.includes(industrySection) {
+		org.IndClassStories = load.industry
+	}
 
-			log.Printf("IndClass: %v", indClassContent)
+	log.Printf("Loaded org %s with %d own, %d parent, %d subsidiary, %d sibling, %d people and %d industry stories", uuid, len(org.Stories), len(org.ParentStories), len(org.SubsidStories), len(org.SiblingStories), len(org.PeopleStories), len(org.IndClassStories))
 
-			if len(indClassContent) > 0 {
-				org.IndClassStories = ocs.enrichContentList(indClassContent)
-			}
-		}
+	// Descriptions set by editors take precedence over the one in the graph.
+	descriptions := descriptionProviders{ocs.descriptions, neoDescriptionProvider{results}}
 
-		recReadsStories := getContentFromRecommendedReads(uuid, ocs.recReadsURL)
+	description, found, err := descriptions.getDescription(uuid)
+
+	if err != nil {
+		return organisation{}, false, err
+	}
 
-		if len(recReadsStories) > 0 {
-			org.RecommendedReadsStories = ocs.enrichContentList(recReadsStories)
+	if found {
+		org.Description = description
+	}
+
+	if opts.includes(recommendedSection) {
+		// Stories are only enriched with standfirsts if that section was
+		// requested; otherwise the headlines from the main query will do.
+		recentStories := opts.pageOf(storiesSection, org.Stories)
+		if len(recentStories) == 0 {
+			recentStories = opts.pageOf(storiesSection, load.stories)
 		}
 
-		description, found := descMap[uuid]
+		recReadsStories, err := ocs.getContentFromRecommendedReads(recommendationContext(org, recentStories), opts.page(recommendedSection), opts.explain)
 
-		if found {
-			org.Description = description
+		if err != nil {
+			log.Printf("Skipping recommended reads for uuid=%s, err=%s", uuid, err)
+			org.Warnings = append(org.Warnings, warning{Section: recommendedSection, Message: err.Error()})
+		} else {
+			org.RecommendedReadsStories = recReadsStories
 		}
+	}
+
+	// Pins, blocks and deduplication apply to all of a section's stories
+	// before it is paged, so that every page is cut from the same list.
+	pinnedTo := ocs.applyPinsAndBlocks(&org, opts)
+	dedupeSections(&org, ocs.sectionPriority, pinnedTo)
+
+	for _, section := range sections {
+		stories := org.section(section)
+		*stories = opts.paginate(&org, section, *stories)
+	}
 
-		resultsMap[uuid] = org
-		log.Printf("Cached org %s", uuid)
+	ocs.enrichSections(&org, opts, enriched)
+	ocs.applyCorrections(&org)
 
+	// Degraded responses are only cached briefly, so that they are soon
+	// retried without every request rebuilding them while a dependency is
+	// down or a story is gone from the Content API.
+	ttl := ocs.cache.ttl
+	if len(org.Warnings) > 0 {
+		ttl = ocs.cache.degradedTTL
+	}
+
+	if ocs.cache.set(key, uuid, org, ttl, load.generation) {
+		log.Printf("Cached org %s with %d warnings for %s", uuid, len(org.Warnings), ttl)
+	} else {
+		log.Printf("Not caching org %s, purged while it was loading", uuid)
 	}
 
 	return org, true, nil
 }
 
-func getContentFromRecommendedReads(uuid string, recReadsURL string) []content {
-	desc, found := descMap[uuid]
+// Patterns binding o to the requested organisation and rel to the related
+// organisations whose stories make up a section.
+const (
+	parentPattern     = `(o:Organisation {uuid:{uuid}})-[:SUB_ORGANISATION_OF]->(rel:Organisation)`
+	subsidiaryPattern = `(o:Organisation {uuid:{uuid}})<-[:SUB_ORGANISATION_OF]-(rel:Organisation)`
+	siblingPattern    = `(o:Organisation {uuid:{uuid}})-[:SUB_ORGANISATION_OF]->(:Organisation)<-[:SUB_ORGANISATION_OF]-(rel:Organisation)`
+)
 
-	if !found {
-		log.Printf("No description found for uuid=%s", uuid)
-		return []content{}
+// relatedOrganisationQuery finds recent content mentioning the organisations
+// matched by pattern, tagging each story with the organisations it mentions.
+func relatedOrganisationQuery(pattern string, uuid string, window timeWindow, result *[]content) *neoism.CypherQuery {
+	return &neoism.CypherQuery{
+		Statement: fmt.Sprintf(`
+		MATCH %s-[:MENTIONS]-(c:Content)
+		WHERE c.publishedDateEpoch > {secondsSinceEpoch} AND c.publishedDateEpoch <= {untilSecondsSinceEpoch} AND rel <> o
+		WITH c, {ID:rel.uuid, Label:rel.prefLabel} as Tags
+		WITH c, collect(Tags) as Tags
+		RETURN c.title as Title, c.uuid as ID, Tags as Tags, c.publishedDate as PublishedDate
+		ORDER BY PublishedDate DESC
+		LIMIT {limit}`, pattern),
+		Parameters: window.sectionProps(uuid),
+		Result:     result,
 	}
+}
 
-	log.Printf("Description=%s", desc)
+// industryStoriesQuery finds recent content mentioning the other
+// organisations in the organisation's industry classification, tagging each
+// story with the organisations it mentions. It finds nothing for an
+// organisation without one.
+func industryStoriesQuery(uuid string, window timeWindow, result *[]content) *neoism.CypherQuery {
+	return &neoism.CypherQuery{
+		Statement: `
+		MATCH (n:Organisation {uuid:{uuid}})
+		OPTIONAL MATCH (n)--(i:IndustryClassification)--(comp:Organisation)-[m:MENTIONS]-(c:Content)
+		WHERE c.publishedDateEpoch > {secondsSinceEpoch} AND c.publishedDateEpoch <= {untilSecondsSinceEpoch}
+		WITH c, {Label:comp.prefLabel} as Tags
+		WHERE c IS NOT NULL
+		WITH c, collect(Tags) as Tags
+		RETURN DISTINCT c.title as Title, c.uuid as ID, Tags as Tags, c.publishedDate as PublishedDate
+		ORDER BY PublishedDate DESC
+		LIMIT {limit}`,
+		Parameters: window.sectionProps(uuid),
+		Result:     result,
+	}
+}
 
-	reqURL := fmt.Sprintf("%s/recommended-reads-api/recommend/contextual/doc?count=5&sort=rel&explain=false", recReadsURL)
-	bodyString := fmt.Sprintf(`{ "doc": {"title": "This is the title", "content": "%s"} }`, desc)
-	request, err := http.NewRequest("POST", reqURL, strings.NewReader(bodyString))
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("Accept", "application/json")
-	if err != nil {
-		log.Printf("Could not create request for reqURL=%s, err=%s", reqURL, err)
+// peopleQuery finds recent content mentioning the organisation's current
+// board members and executives. Each story is tagged with the people it
+// mentions and their roles, and stories about the people most often written
+// about alongside the organisation come first.
+func peopleQuery(uuid string, window timeWindow, result *[]content) *neoism.CypherQuery {
+	return &neoism.CypherQuery{
+		Statement: `
+		MATCH (o:Organisation {uuid:{uuid}})<-[:HAS_ORGANISATION]-(m:Membership)-[:HAS_MEMBER]->(p:Person)
+		WHERE m.terminationDate IS NULL
+		WITH o, m, p, size((p)<-[:MENTIONS]-(:Content)-[:MENTIONS]->(o)) as annCount
+		MATCH (p)<-[:MENTIONS]-(c:Content)
+		WHERE c.publishedDateEpoch > {secondsSinceEpoch} AND c.publishedDateEpoch <= {untilSecondsSinceEpoch}
+		WITH c, max(annCount) as annCount, collect({ID:p.uuid, Label:p.prefLabel, Role:m.prefLabel}) as Tags
+		RETURN c.title as Title, c.uuid as ID, Tags as Tags, c.publishedDate as PublishedDate
+		ORDER BY annCount DESC, PublishedDate DESC
+		LIMIT {limit}`,
+		Parameters: window.sectionProps(uuid),
+		Result:     result,
 	}
-	resp, err := httpClient.Do(request)
-	defer resp.Body.Close()
-	if err != nil {
-		log.Printf("Error for reqURL=%s, err=%s", reqURL, err)
+}
+
+// recommendationContext composes the document recommendations are sought
+// for from what we know of the organisation: its names, its description and
+// the headlines and standfirsts of its recent stories.
+func recommendationContext(org organisation, recentStories []content) recommendedReadsDoc {
+	paragraphs := []string{}
+
+	if org.Profile != nil && len(org.Profile.Aliases) > 0 {
+		paragraphs = append(paragraphs, strings.Join(org.Profile.Aliases, ", "))
 	}
-	log.Printf("Response=%v", resp.StatusCode)
-	if http.StatusOK != resp.StatusCode && http.StatusNotFound != resp.StatusCode {
-		log.Printf("Unexpected status code for reqURL=%s, code=%v", reqURL, resp.StatusCode)
+
+	if org.Description != "" {
+		paragraphs = append(paragraphs, org.Description)
 	}
 
-	if err != nil {
-		log.Printf("Error for reqURL=%s, err=%s", reqURL, err)
+	for _, story := range recentStories {
+		if story.Title != "" {
+			paragraphs = append(paragraphs, story.Title)
+		}
+		if story.Standfirst != "" {
+			paragraphs = append(paragraphs, story.Standfirst)
+		}
 	}
 
-	target := recommendedReads{}
+	return recommendedReadsDoc{Title: org.Title, Content: strings.Join(paragraphs, "\n\n")}
+}
+
+// recommendedDedupeAllowance is how many more recommended reads are asked
+// for than the requested page needs, to make up for those deduplication
+// removes because they are in another section.
+const recommendedDedupeAllowance = 5
+
+// getContentFromRecommendedReads asks the recommender for enough stories to
+// fill page p of recommended reads, and tell whether there is another. The
+// recommender has no offset, so the pages before p are asked for too.
+func (ocs simpleOrganisationContentService) getContentFromRecommendedReads(doc recommendedReadsDoc, p page, explain bool) ([]content, error) {
+	log.Printf("Recommended reads context title=%s", doc.Title)
 
-	json.NewDecoder(resp.Body).Decode(&target)
+	articles, err := ocs.recReads.recommend(doc, p.offset+p.limit+1+recommendedDedupeAllowance, explain)
+
+	if err != nil {
+		return nil, err
+	}
 
 	contList := []content{}
 
-	for _, art := range target.Articles {
+	for _, art := range articles {
 		cont := content{
 			Title: art.Title,
 			ID:    art.ID,
 		}
+		if explain {
+			cont.Recommendation = &recommendation{
+				Score:       art.Score,
+				Popularity:  art.Popularity,
+				Published:   art.Published,
+				Explanation: art.Explanation,
+			}
+		}
 		contList = append(contList, cont)
 	}
 
-	log.Printf("RecommendedReads stories=%s", contList)
-
-	return contList
+	log.Printf("RecommendedReads stories=%v", contList)
 
+	return contList, nil
 }
 
-func (ocs simpleOrganisationContentService) enrichContent(story content, index int, ch chan<- contentResult) {
-	reqURL := fmt.Sprintf("http://api.ft.com/enrichedcontent/%s", story.ID)
-
-	enriched := ocs.getEnrichedContent(reqURL)
+// enrichContent adds the standfirst, tags and main image from the Content
+// API to story. If the content can't be fetched the story is returned as it
+// was, along with the error; if only the image can't be, the story is
+// returned without one.
+func (ocs simpleOrganisationContentService) enrichContent(story content, spec imageSpec) (content, error) {
+	enriched, err := ocs.contentAPI.get(ocs.contentAPI.enrichedContentURL(story.ID))
+	if err != nil {
+		return story, err
+	}
 
 	log.Printf("Standfirst=%s", enriched.Standfirst)
 
 	story.Standfirst = enriched.Standfirst
 
-	for _, annotation := range enriched.Annotations {
-		if annotation.Type == "GENRE" && annotation.PrefLabel == "Comment" {
-			commentTag := tag{URL: "https://www.ft.com/opinion", Label: "Comment"}
-			story.Tags = append(story.Tags, commentTag)
-		}
+	if enriched.Standout.EditorsChoice || enriched.Standout.Exclusive || enriched.Standout.Scoop {
+		standout := enriched.Standout
+		story.Standout = &standout
 	}
 
-	// get the image
-	if enriched.MainImage.ID != "" {
-		imageSet := ocs.getEnrichedContent(enriched.MainImage.ID)
-
-		members := imageSet.Members
-
-		image := ocs.getEnrichedContent(members[0].ID)
-		story.ImageURL = image.BinaryURL
+	// Stories that didn't come from the graph, such as pinned ones, only
+	// have an ID to start with.
+	if story.Title == "" {
+		story.Title = enriched.Title
 	}
-
-	if story.ID == "ea207b7c-7020-3255-88d3-da429b6b8013" {
-		story.ImageURL = "http://www.etbtravelnews.com/wp-content/uploads/2016/11/The-ultimate-milk-run-Qantas-1024x700.jpg"
+	if story.PublishedDate == "" {
+		story.PublishedDate = enriched.PublishedDate
 	}
 
-	ch <- contentResult{index, story}
-}
+	story.Tags = append(story.Tags, ocs.tagRules.tags(enriched.Annotations)...)
 
-func (ocs simpleOrganisationContentService) getEnrichedContent(reqURL string) enrichedContent {
-	request, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		log.Printf("Could not create request for reqURL=%s, err=%s", reqURL, err)
-	}
-	request.Header.Set("X-Api-Key", ocs.apiKey)
-	resp, err := httpClient.Do(request)
-	defer resp.Body.Close()
-	if err != nil {
-		log.Printf("Error for reqURL=%s, err=%s", reqURL, err)
+	// get the image
+	if enriched.MainImage.ID != "" {
+		images, best, err := ocs.getImages(enriched.MainImage.ID, spec)
+		if err != nil {
+			return story, fmt.Errorf("main image: %s", err)
+		}
+		story.Images = images
+		story.ImageURL = best.URL
 	}
 
-	enriched := enrichedContent{}
+	return story, nil
+}
 
-	json.NewDecoder(resp.Body).Decode(&enriched)
+// enrichSections enriches the stories of every section, other than those
+// already enriched, on the shared worker pool. It records a warning on org
+// for each story that couldn't be, including those enriched earlier.
+func (ocs simpleOrganisationContentService) enrichSections(org *organisation, opts queryOptions, enriched map[string]error) {
+	stories := []*content{}
+	storySections := []string{}
+
+	for _, section := range sections {
+		sectionStories := *org.section(section)
+		for i := range sectionStories {
+			stories = append(stories, &sectionStories[i])
+			storySections = append(storySections, section)
+		}
+	}
 
-	return enriched
-}
+	jobs := []func(){}
+	errs := make([]error, len(stories))
 
-func (ocs simpleOrganisationContentService) enrichContentList(storyList []content) []content {
+	for i := range stories {
+		i := i
+		if err, found := enriched[stories[i].ID]; found {
+			errs[i] = err
+			continue
+		}
+		jobs = append(jobs, func() {
+			*stories[i], errs[i] = ocs.enrichContent(*stories[i], opts.image)
+		})
+	}
 
-	ch := make(chan contentResult)
+	ocs.enrichPool.run(jobs)
 
-	for i, story := range storyList {
-		go ocs.enrichContent(story, i, ch)
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("Could not enrich story %s in %s, err=%s", stories[i].ID, storySections[i], err)
+			org.Warnings = append(org.Warnings, warning{Section: storySections[i], ID: stories[i].ID, Message: err.Error()})
+		}
 	}
+}
 
-	for i := 0; i < len(storyList); i++ {
-		result := <-ch
-		storyList[result.index] = result.story
+// enrichContentList enriches the stories of section in place on the shared
+// worker pool, adding to warnings for each story that couldn't be.
+func (ocs simpleOrganisationContentService) enrichContentList(warnings *[]warning, section string, storyList []content, spec imageSpec) []content {
+	for i, err := range ocs.enrichStories(storyList, spec) {
+		if err != nil {
+			log.Printf("Could not enrich story %s in %s, err=%s", storyList[i].ID, section, err)
+			*warnings = append(*warnings, warning{Section: section, ID: storyList[i].ID, Message: err.Error()})
+		}
 	}
 
 	return storyList
 }
 
-type contentResult struct {
-	index int
-	story content
+// enrichStories enriches storyList in place on the shared worker pool,
+// returning the error, if any, for each story.
+func (ocs simpleOrganisationContentService) enrichStories(storyList []content, spec imageSpec) []error {
+	jobs := make([]func(), len(storyList))
+	errs := make([]error, len(storyList))
+
+	for i := range storyList {
+		i := i
+		jobs[i] = func() {
+			storyList[i], errs[i] = ocs.enrichContent(storyList[i], spec)
+		}
+	}
+
+	ocs.enrichPool.run(jobs)
+
+	return errs
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmcvetta/neoism"
+)
+
+const (
+	defaultSubsidiaryDepth = 1
+	maxSubsidiaryDepth     = 5
+)
+
+// subsidiaryNode is an organisation in a group structure, with the
+// organisations that are directly its subsidiaries.
+type subsidiaryNode struct {
+	ID           string            `json:"id"`
+	PrefLabel    string            `json:"prefLabel"`
+	AnnCount     int               `json:"annotationsCount"`
+	Stories      []content         `json:"stories,omitempty"`
+	Subsidiaries []*subsidiaryNode `json:"subsidiaries,omitempty"`
+}
+
+// subsidiaryEdge is a subsidiary found depth SUB_ORGANISATION_OF hops below
+// the root, through its parent Parent.
+type subsidiaryEdge struct {
+	Parent    string
+	ID        string
+	PrefLabel string
+	AnnCount  int
+	Depth     int
+}
+
+type subsidiaryTreeResult struct {
+	ID        string
+	PrefLabel string
+	AnnCount  int
+	Edges     []subsidiaryEdge
+}
+
+// subsidiaryTreeQuery finds every organisation up to depth levels below
+// uuid, with the annotation counts used for subsidiaries in the profile.
+// Variable length bounds can't be parameters, so depth is written into the
+// statement.
+func subsidiaryTreeQuery(uuid string, depth int, result *[]subsidiaryTreeResult) *neoism.CypherQuery {
+	return &neoism.CypherQuery{
+		Statement: fmt.Sprintf(`
+		MATCH (o:Organisation {uuid:{uuid}})
+		OPTIONAL MATCH p=(o)<-[:SUB_ORGANISATION_OF*1..%d]-(sub:Organisation)
+		WITH o, p, sub, nodes(p)[length(p)-1] as parent
+		WITH o, collect(DISTINCT {Parent:parent.uuid, ID:sub.uuid, PrefLabel:sub.prefLabel, AnnCount:size((:Content)-[:MENTIONS]->(sub)), Depth:length(p)}) as edges
+		RETURN o.uuid as ID, o.prefLabel as PrefLabel, size((:Content)-[:MENTIONS]->(o)) as AnnCount, [e IN edges WHERE e.ID IS NOT NULL] as Edges`, depth),
+		Parameters: neoism.Props{"uuid": uuid},
+		Result:     result,
+	}
+}
+
+// latestStoriesQuery finds the most recent stories in window mentioning
+// each of the organisations uuids.
+func latestStoriesQuery(uuids []string, window timeWindow, limit int, result *[]organisation) *neoism.CypherQuery {
+	props := neoism.Props{"uuids": uuids, "secondsSinceEpoch": window.since, "untilSecondsSinceEpoch": window.until, "limit": limit}
+
+	return &neoism.CypherQuery{
+		Statement: `
+		UNWIND {uuids} as uuid
+		MATCH (o:Organisation {uuid:uuid})-[:MENTIONS]-(c:Content)
+		WHERE c.publishedDateEpoch > {secondsSinceEpoch} AND c.publishedDateEpoch <= {untilSecondsSinceEpoch}
+		WITH o, c ORDER BY c.publishedDateEpoch DESC
+		WITH o, collect({Title:c.title, ID:c.uuid, PublishedDate:c.publishedDate}) as stories
+		RETURN o.uuid as ID, stories[..{limit}] as Stories`,
+		Parameters: props,
+		Result:     result,
+	}
+}
+
+// getSubsidiaryTree builds the group structure below the organisation down
+// to depth levels. Each organisation appears once, under the parent through
+// which it is nearest the root. If stories is positive each node carries up
+// to that many of its latest stories in the options' time window.
+func (ocs simpleOrganisationContentService) getSubsidiaryTree(uuid string, depth int, stories int, opts queryOptions) (*subsidiaryNode, bool, error) {
+	results := []subsidiaryTreeResult{}
+
+	if err := ocs.conn.CypherBatch([]*neoism.CypherQuery{subsidiaryTreeQuery(uuid, depth, &results)}); err != nil {
+		return nil, false, err
+	}
+
+	if len(results) == 0 {
+		return nil, false, nil
+	}
+
+	root, nodes := buildSubsidiaryTree(results[0])
+
+	if stories > 0 {
+		uuids := make([]string, 0, len(nodes))
+		for id := range nodes {
+			uuids = append(uuids, id)
+		}
+
+		latest := []organisation{}
+
+		if err := ocs.conn.CypherBatch([]*neoism.CypherQuery{latestStoriesQuery(uuids, opts.bounds(time.Now()), stories, &latest)}); err != nil {
+			return nil, false, err
+		}
+
+		for _, result := range latest {
+			if node, found := nodes[result.ID]; found {
+				node.Stories = result.Stories
+			}
+		}
+	}
+
+	return root, true, nil
+}
+
+// buildSubsidiaryTree arranges the edges of result below its root, placing
+// each organisation once under the parent through which it is nearest the
+// root, and orders every node's subsidiaries most annotated first. It
+// returns the root and every node in the tree by ID.
+func buildSubsidiaryTree(result subsidiaryTreeResult) (*subsidiaryNode, map[string]*subsidiaryNode) {
+	root := &subsidiaryNode{ID: result.ID, PrefLabel: result.PrefLabel, AnnCount: result.AnnCount}
+	nodes := map[string]*subsidiaryNode{root.ID: root}
+
+	edges := append([]subsidiaryEdge(nil), result.Edges...)
+	sort.SliceStable(edges, func(i, j int) bool { return edges[i].Depth < edges[j].Depth })
+
+	for _, edge := range edges {
+		parent, found := nodes[edge.Parent]
+		if !found {
+			continue
+		}
+		if _, placed := nodes[edge.ID]; placed {
+			continue
+		}
+
+		node := &subsidiaryNode{ID: edge.ID, PrefLabel: edge.PrefLabel, AnnCount: edge.AnnCount}
+		nodes[edge.ID] = node
+		parent.Subsidiaries = append(parent.Subsidiaries, node)
+	}
+
+	for _, node := range nodes {
+		subs := node.Subsidiaries
+		sort.SliceStable(subs, func(i, j int) bool {
+			if subs[i].AnnCount != subs[j].AnnCount {
+				return subs[i].AnnCount > subs[j].AnnCount
+			}
+			return subs[i].PrefLabel < subs[j].PrefLabel
+		})
+	}
+
+	return root, nodes
+}
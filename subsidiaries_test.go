@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// treeShape writes node and its subsidiaries as id(sub,sub(...)).
+func treeShape(node *subsidiaryNode) string {
+	if len(node.Subsidiaries) == 0 {
+		return node.ID
+	}
+
+	subs := []string{}
+	for _, sub := range node.Subsidiaries {
+		subs = append(subs, treeShape(sub))
+	}
+	return node.ID + "(" + strings.Join(subs, ",") + ")"
+}
+
+func TestBuildSubsidiaryTree(t *testing.T) {
+	edge := func(parent, id string, annCount, depth int) subsidiaryEdge {
+		return subsidiaryEdge{Parent: parent, ID: id, PrefLabel: strings.ToUpper(id), AnnCount: annCount, Depth: depth}
+	}
+
+	tests := []struct {
+		name  string
+		edges []subsidiaryEdge
+		shape string
+		nodes int
+	}{
+		{"no subsidiaries", nil, "root", 1},
+		{"one level", []subsidiaryEdge{edge("root", "a", 1, 1)}, "root(a)", 2},
+		{
+			"deeper levels in any order",
+			[]subsidiaryEdge{edge("b", "c", 0, 3), edge("a", "b", 0, 2), edge("root", "a", 0, 1)},
+			"root(a(b(c)))", 4,
+		},
+		{
+			"placed under the parent nearest the root",
+			[]subsidiaryEdge{edge("a", "b", 0, 2), edge("root", "a", 0, 1), edge("root", "b", 0, 1)},
+			"root(a,b)", 3,
+		},
+		{
+			"listed once when found twice at the same depth",
+			[]subsidiaryEdge{edge("root", "a", 0, 1), edge("a", "c", 0, 2), edge("root", "b", 0, 1), edge("b", "c", 0, 2)},
+			"root(a(c),b)", 4,
+		},
+		{
+			"cycle back to the root",
+			[]subsidiaryEdge{edge("root", "a", 0, 1), edge("a", "root", 0, 2)},
+			"root(a)", 2,
+		},
+		{
+			"parent outside the tree",
+			[]subsidiaryEdge{edge("elsewhere", "a", 0, 1)},
+			"root", 1,
+		},
+		{
+			"most annotated first, then by label",
+			[]subsidiaryEdge{edge("root", "c", 1, 1), edge("root", "b", 5, 1), edge("root", "a", 1, 1), edge("b", "e", 0, 2), edge("b", "d", 0, 2)},
+			"root(b(d,e),a,c)", 6,
+		},
+	}
+
+	for _, test := range tests {
+		root, nodes := buildSubsidiaryTree(subsidiaryTreeResult{ID: "root", PrefLabel: "Root", AnnCount: 7, Edges: test.edges})
+
+		assert.Equal(t, test.shape, treeShape(root), test.name)
+		assert.Len(t, nodes, test.nodes, test.name)
+		assert.Equal(t, 7, root.AnnCount, test.name)
+		assert.Equal(t, root, nodes["root"], test.name)
+	}
+}
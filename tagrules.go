@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+)
+
+// tagRule turns a matching annotation into a tag. Empty Type, Predicate and
+// PrefLabel match anything; Predicate may be given in full or as the last
+// segment of the predicate URI, e.g. "about". Label defaults to the
+// annotation's prefLabel, and {uuid} in URL is replaced by the annotated
+// concept's UUID. Tags with a higher Priority are listed first.
+type tagRule struct {
+	Type      string `json:"type,omitempty"`
+	Predicate string `json:"predicate,omitempty"`
+	PrefLabel string `json:"prefLabel,omitempty"`
+	Label     string `json:"label,omitempty"`
+	URL       string `json:"url"`
+	Priority  int    `json:"priority,omitempty"`
+}
+
+// tagRules are tried in order against each annotation, the first match
+// producing its tag.
+type tagRules []tagRule
+
+func loadTagRules(path string) (tagRules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules := tagRules{}
+	if err := json.NewDecoder(f).Decode(&rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r tagRule) matches(a annotation) bool {
+	if r.Type != "" && r.Type != a.Type {
+		return false
+	}
+	if r.Predicate != "" && r.Predicate != a.Predicate && !strings.HasSuffix(a.Predicate, "/"+r.Predicate) {
+		return false
+	}
+	if r.PrefLabel != "" && r.PrefLabel != a.PrefLabel {
+		return false
+	}
+	return true
+}
+
+func (r tagRule) tag(a annotation) tag {
+	label := r.Label
+	if label == "" {
+		label = a.PrefLabel
+	}
+
+	uuid := a.ID[strings.LastIndex(a.ID, "/")+1:]
+
+	return tag{
+		URL:      strings.Replace(r.URL, "{uuid}", uuid, -1),
+		Label:    label,
+		Priority: r.Priority,
+	}
+}
+
+// tags maps annotations to tags, highest priority first, leaving out
+// duplicates and annotations no rule matches.
+func (rules tagRules) tags(annotations []annotation) []tag {
+	tags := []tag{}
+	seen := map[tag]bool{}
+
+	for _, a := range annotations {
+		for _, rule := range rules {
+			if !rule.matches(a) {
+				continue
+			}
+
+			t := rule.tag(a)
+			if !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+			break
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Priority > tags[j].Priority })
+
+	return tags
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagRules(t *testing.T) {
+	rules := tagRules{
+		{Type: "GENRE", PrefLabel: "Comment", Label: "Comment", URL: "https://www.ft.com/opinion", Priority: 100},
+		{Type: "BRAND", URL: "https://www.ft.com/stream/brandId/{uuid}", Priority: 50},
+		{Type: "PERSON", Predicate: "hasAuthor", URL: "https://www.ft.com/stream/authorsId/{uuid}", Priority: 10},
+		{Type: "PERSON", Predicate: "http://www.ft.com/ontology/annotation/about", URL: "https://www.ft.com/topics/people/{uuid}"},
+	}
+
+	comment := annotation{ID: "http://api.ft.com/things/c1", Type: "GENRE", PrefLabel: "Comment"}
+	news := annotation{ID: "http://api.ft.com/things/n1", Type: "GENRE", PrefLabel: "News"}
+	lex := annotation{ID: "http://api.ft.com/things/b1", Type: "BRAND", PrefLabel: "Lex"}
+	author := annotation{ID: "http://api.ft.com/things/p1", Type: "PERSON", PrefLabel: "Jane Doe", Predicate: "http://www.ft.com/ontology/annotation/hasAuthor"}
+	subject := annotation{ID: "http://api.ft.com/things/p2", Type: "PERSON", PrefLabel: "John Roe", Predicate: "http://www.ft.com/ontology/annotation/about"}
+	mentioned := annotation{ID: "http://api.ft.com/things/p3", Type: "PERSON", PrefLabel: "Joe Bloggs", Predicate: "http://www.ft.com/ontology/annotation/mentions"}
+
+	commentTag := tag{URL: "https://www.ft.com/opinion", Label: "Comment", Priority: 100}
+	lexTag := tag{URL: "https://www.ft.com/stream/brandId/b1", Label: "Lex", Priority: 50}
+	authorTag := tag{URL: "https://www.ft.com/stream/authorsId/p1", Label: "Jane Doe", Priority: 10}
+	subjectTag := tag{URL: "https://www.ft.com/topics/people/p2", Label: "John Roe"}
+
+	tests := []struct {
+		name        string
+		annotations []annotation
+		want        []tag
+	}{
+		{"no annotations", nil, []tag{}},
+		{"unmatched prefLabel", []annotation{news}, []tag{}},
+		{"unmatched predicate", []annotation{mentioned}, []tag{}},
+		{"fixed label and URL", []annotation{comment}, []tag{commentTag}},
+		{"label and uuid from the annotation", []annotation{lex}, []tag{lexTag}},
+		{"predicate by last segment", []annotation{author}, []tag{authorTag}},
+		{"predicate in full", []annotation{subject}, []tag{subjectTag}},
+		{"highest priority first", []annotation{subject, author, comment, lex}, []tag{commentTag, lexTag, authorTag, subjectTag}},
+		{"duplicates left out", []annotation{lex, comment, lex}, []tag{commentTag, lexTag}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, rules.tags(test.annotations), test.name)
+	}
+}
+
+func TestTagRulesFirstMatchWins(t *testing.T) {
+	rules := tagRules{
+		{Type: "GENRE", PrefLabel: "Comment", Label: "Opinion", URL: "https://www.ft.com/opinion"},
+		{Type: "GENRE", URL: "https://www.ft.com/genre/{uuid}"},
+	}
+
+	got := rules.tags([]annotation{{ID: "http://api.ft.com/things/c1", Type: "GENRE", PrefLabel: "Comment"}})
+
+	assert.Equal(t, []tag{{URL: "https://www.ft.com/opinion", Label: "Opinion"}}, got)
+}
+
+func TestLoadTagRules(t *testing.T) {
+	rules, err := loadTagRules("tag-rules.json")
+
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+
+	_, err = loadTagRules("missing.json")
+	assert.Error(t, err)
+}